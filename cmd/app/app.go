@@ -1,26 +1,65 @@
 package main
 
 import (
+	"context"
 	"scalingo/internal/controller"
+	"scalingo/internal/core/service"
+	"scalingo/internal/infra/repositories"
+	"scalingo/internal/infra/store"
 
 	"os"
 	"os/signal"
 	"syscall"
+
+	log "github.com/sirupsen/logrus"
 )
 
 func ProvideApp(
+	ctx context.Context,
 	httpServer *controller.HTTPService,
+	eventsPoller *repositories.EventsPoller,
+	repoStore *store.Bolt,
+	crawler *service.Crawler,
+	github *repositories.Github,
 ) *App {
 	return &App{
-		httpServer: *httpServer,
+		ctx:          ctx,
+		httpServer:   *httpServer,
+		eventsPoller: eventsPoller,
+		repoStore:    repoStore,
+		crawler:      crawler,
+		github:       github,
 	}
 }
 
 type App struct {
-	httpServer controller.HTTPService
+	ctx          context.Context
+	httpServer   controller.HTTPService
+	eventsPoller *repositories.EventsPoller
+	repoStore    *store.Bolt
+	crawler      *service.Crawler
+	github       *repositories.Github
 }
 
 func (a *App) Start() {
+	defer func() {
+		if err := a.github.Close(); err != nil {
+			log.Error(err.Error())
+		}
+	}()
+	if a.eventsPoller != nil {
+		a.eventsPoller.Start(a.ctx)
+		defer a.eventsPoller.Stop()
+	}
+	if a.repoStore != nil {
+		a.repoStore.Start(a.ctx)
+		defer func() { _ = a.repoStore.Close() }()
+	}
+	if a.crawler != nil {
+		a.crawler.Start(a.ctx)
+		defer a.crawler.Stop()
+	}
+
 	a.httpServer.StartHTTPServer()
 	defer a.httpServer.ShutdownHTTPServer()
 
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"scalingo/internal/core/domain"
+	"scalingo/internal/core/dto"
+	"scalingo/internal/infra/config"
+	"scalingo/internal/infra/githubfake"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	s "github.com/stretchr/testify/suite"
+)
+
+// AppSuite serves the routes wired as in production, the real GitHub adapter requesting a fake GitHub
+type AppSuite struct {
+	s.Suite
+	github *githubfake.Server
+	config *config.Config
+}
+
+// SetupTest lists the repositories 1001 to 1150 above the head 1000, every tenth one being a fork, every third one
+// without description, the Go repositories up to 1100 and the Rust ones above
+func (suite *AppSuite) SetupTest() {
+	suite.github = githubfake.New(suite.T())
+	head := &githubfake.Repository{ID: 1000, FullName: "john_doe/head"}
+	suite.github.AddRepository(head)
+	suite.github.AddEvent(githubfake.CreateEvent(head))
+	for id := 1001; id <= 1150; id++ {
+		repository := &githubfake.Repository{
+			ID:        id,
+			FullName:  "user" + strconv.Itoa(id) + "/repo-" + strconv.Itoa(id),
+			Fork:      id%10 == 0,
+			Languages: map[string]int{"Go": id},
+		}
+		if id%3 != 0 {
+			repository.Description = githubfake.Description("Repository " + strconv.Itoa(id))
+		}
+		if id > 1100 {
+			repository.Languages = map[string]int{"Rust": id}
+		}
+		if id%2 == 0 {
+			repository.License = "MIT"
+		}
+		suite.github.AddRepository(repository)
+	}
+
+	suite.config = &config.Config{
+		GinMode:                gin.TestMode,
+		GitHubURL:              suite.github.APIURL(),
+		GitHubMaxRedirects:     3,
+		LatestCreatedRepoRetry: 1,
+		OutputSize:             5,
+		ProcessingBatchSize:    10,
+	}
+}
+
+func (suite *AppSuite) request(method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	router := InitializeRouter(context.Background(), suite.config)
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func (suite *AppSuite) list(body string) []*domain.ListRepoOutput {
+	recorder := suite.request(http.MethodGet, "/repositories", body, nil)
+	assert.Equal(suite.T(), http.StatusOK, recorder.Code, recorder.Body.String())
+
+	var repositories []*domain.ListRepoOutput
+	assert.NoError(suite.T(), jsoniter.Unmarshal(recorder.Body.Bytes(), &repositories))
+	return repositories
+}
+
+func (suite *AppSuite) TestListRepositories_Renamed() {
+	suite.github.Rename("user1004/repo-1004", "jane_doe/renamed")
+
+	repositories := suite.list(`{"name_contains":"repo-1004"}`)
+
+	assert.Equal(suite.T(), 1, len(repositories))
+	assert.Equal(suite.T(), "jane_doe/renamed", repositories[0].FullName)
+	assert.Equal(suite.T(), "user1004/repo-1004", repositories[0].PreviousFullName)
+	assert.Equal(suite.T(), "MIT", repositories[0].License)
+	assert.Equal(suite.T(), map[string]int{"Go": 1004}, repositories[0].Languages)
+	assert.Equal(suite.T(), "Repository 1004", repositories[0].Description)
+	assert.Equal(suite.T(), 1, suite.github.Count("/repos/user1004/repo-1004"))
+	assert.Equal(suite.T(), 1, suite.github.Count("/repositories/1004"))
+}
+
+func (suite *AppSuite) TestListRepositories_Missing() {
+	suite.github.Fail("/repos/user1005/repo-1005/languages", http.StatusNotFound)
+
+	repositories := suite.list(`{"name_contains":"repo-1005"}`)
+
+	assert.Equal(suite.T(), 1, len(repositories))
+	assert.Equal(suite.T(), "user1005/repo-1005", repositories[0].FullName)
+	assert.Empty(suite.T(), repositories[0].Description)
+	assert.Empty(suite.T(), repositories[0].Languages)
+	assert.Empty(suite.T(), repositories[0].License)
+}
+
+// TestListRepositories_NextPage lists beyond the first page of 90 repositories, the Rust ones all being on the second
+func (suite *AppSuite) TestListRepositories_NextPage() {
+	suite.config.OutputSize = 100
+
+	repositories := suite.list(`{"language":"rust"}`)
+
+	assert.Equal(suite.T(), 45, len(repositories)) // 1101 to 1150, forks excluded
+	for _, repository := range repositories {
+		assert.Contains(suite.T(), repository.Languages, "Rust")
+	}
+	assert.Equal(suite.T(), 2, suite.github.Count("/repositories"))
+	assert.Contains(suite.T(), suite.github.Requests(), "/repositories?since=1000")
+	assert.Contains(suite.T(), suite.github.Requests(), "/repositories?since=1100") // the fork 1100 ending the first page is skipped with it
+}
+
+// TestListRepositories_SearchHead lists the page behind the head found by binary search, 1150 being the head
+func (suite *AppSuite) TestListRepositories_SearchHead() {
+	suite.config.GitHubHeadStrategy = "search"
+
+	repositories := suite.list(`{}`)
+
+	assert.Equal(suite.T(), 5, len(repositories))
+	assert.Contains(suite.T(), suite.github.Requests(), "/repositories?since=1050")
+	assert.Equal(suite.T(), 0, suite.github.Count("/events"))
+	assert.Less(suite.T(), suite.github.Count("/repositories"), 30)
+}
+
+func (suite *AppSuite) TestListRepositories_RateLimited() {
+	suite.github.SetRateLimit(60, 0, time.Now().Add(time.Hour))
+
+	recorder := suite.request(http.MethodGet, "/repositories", `{}`, nil)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, recorder.Code)
+	assert.Equal(suite.T(), 0, suite.github.Count("/repositories"))
+}
+
+func (suite *AppSuite) TestTokenUsage() {
+	suite.config.GitHubCredentials = true
+	suite.config.GitHubToken = "first"
+	suite.config.AdminToken = "admin"
+	suite.github.SetRateLimit(5000, 10, time.Now().Add(time.Hour))
+	router := InitializeRouter(context.Background(), suite.config)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/repositories", strings.NewReader(`{"name_contains":"repo-1001"}`)),
+		httptest.NewRequest(http.MethodGet, "/admin/tokens", nil),
+	} {
+		req.Header.Set("Authorization", "Bearer admin")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		assert.Equal(suite.T(), http.StatusOK, recorder.Code)
+
+		if req.URL.Path == "/admin/tokens" {
+			var usage []*dto.TokenUsage
+			assert.NoError(suite.T(), jsoniter.Unmarshal(recorder.Body.Bytes(), &usage))
+			assert.Equal(suite.T(), 1, len(usage))
+			assert.Equal(suite.T(), 5000, usage[0].Limit)
+			assert.False(suite.T(), usage[0].Active)
+			assert.Equal(suite.T(), "exhausted", usage[0].DisabledReason)
+		}
+	}
+}
+
+func (suite *AppSuite) TestAdmin_WithoutToken() {
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		recorder := suite.request(method, "/admin/snapshot", "", nil)
+		assert.Equal(suite.T(), http.StatusNotFound, recorder.Code)
+	}
+
+	suite.config.AdminToken = "admin"
+	recorder := suite.request(http.MethodGet, "/admin/tokens", "", nil)
+	assert.Equal(suite.T(), http.StatusUnauthorized, recorder.Code)
+}
+
+func TestAppSuite(t *testing.T) {
+	s.Run(t, new(AppSuite))
+}
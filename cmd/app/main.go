@@ -1,6 +1,12 @@
 package main
 
+import "os"
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "snapshot" {
+		os.Exit(InitializeSnapshotCommand().Run(os.Args[2:]))
+	}
+
 	e := InitializeApp()
 	e.Start()
 }
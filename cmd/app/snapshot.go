@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"scalingo/internal/core/domain"
+	"scalingo/internal/core/port"
+	"scalingo/internal/core/service"
+	"scalingo/internal/infra/store"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const snapshotUsage = `usage:
+  app snapshot export [-output file] [-source github,gitlab] [-language go] [-license mit] [-name-contains x]
+                      [-description-contains x] [-min-size n] [-max-size n]
+  app snapshot import file`
+
+func ProvideSnapshotCommand(repoStore *store.Bolt, snapshotService *service.SnapshotService) *SnapshotCommand {
+	return &SnapshotCommand{repoStore: repoStore, snapshotService: snapshotService}
+}
+
+// ProvideNoSearch leaves the snapshot command without search index, the index of the server is filled from the store
+// at startup
+func ProvideNoSearch() port.SearchIndexInterface {
+	return nil
+}
+
+// SnapshotCommand exports and imports the snapshots of the store from the command line, while the server is stopped
+// as the store file can only be opened once
+type SnapshotCommand struct {
+	repoStore       *store.Bolt
+	snapshotService *service.SnapshotService
+}
+
+// Run executes the snapshot subcommand and returns the exit code
+func (s *SnapshotCommand) Run(args []string) int {
+	if s.repoStore != nil {
+		defer func() { _ = s.repoStore.Close() }()
+	}
+
+	var err error
+	switch {
+	case len(args) > 0 && args[0] == "export":
+		err = s.export(args[1:])
+	case len(args) == 2 && args[0] == "import": //nolint: gomnd
+		err = s.importFile(args[1])
+	default:
+		fmt.Fprintln(os.Stderr, snapshotUsage)
+		return 2 //nolint: gomnd
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "snapshot "+args[0]+" failed: "+err.Error())
+		return 1
+	}
+	return 0
+}
+
+func (s *SnapshotCommand) export(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	output := flags.String("output", "-", "archive file, - for the standard output")
+	sources := flags.String("source", "", "comma separated sources, all the stored ones when empty")
+	filters := &domain.ListRepoInput{}
+	flags.StringVar(&filters.Language, "language", "", "language filter")
+	flags.StringVar(&filters.License, "license", "", "license filter")
+	flags.StringVar(&filters.NameContains, "name-contains", "", "name filter")
+	flags.StringVar(&filters.DescriptionContains, "description-contains", "", "description filter")
+	flags.Int64Var(&filters.MinSize, "min-size", 0, "minimum size in bytes")
+	flags.Int64Var(&filters.MaxSize, "max-size", 0, "maximum size in bytes")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	exportInput := &domain.SnapshotExportInput{}
+	for _, source := range strings.Split(*sources, ",") {
+		if source = strings.TrimSpace(source); source != "" {
+			exportInput.Sources = append(exportInput.Sources, source)
+		}
+	}
+	if *filters != (domain.ListRepoInput{}) {
+		exportInput.Filters = filters
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	manifest, err := s.snapshotService.Export(w, exportInput)
+	if err != nil {
+		if *output != "-" {
+			_ = os.Remove(*output)
+		}
+		return err
+	}
+	return printJSON(manifest)
+}
+
+func (s *SnapshotCommand) importFile(path string) error {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	output, err := s.snapshotService.Import(r)
+	if err != nil {
+		return err
+	}
+	return printJSON(output)
+}
+
+// printJSON reports the result on the standard error, the standard output may hold the archive
+func printJSON(value any) error {
+	serialized, err := jsoniter.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return errors.New("error while serializing snapshot report: " + err.Error())
+	}
+	_, err = fmt.Fprintln(os.Stderr, string(serialized))
+	return err
+}
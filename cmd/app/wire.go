@@ -4,31 +4,81 @@ package main
 
 import (
 	"context"
+	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
 	"scalingo/internal/controller"
 	"scalingo/internal/core/port"
 	"scalingo/internal/core/service"
+	"scalingo/internal/infra/cache"
 	"scalingo/internal/infra/repositories"
+	"scalingo/internal/infra/search"
+	"scalingo/internal/infra/snapshot"
+	"scalingo/internal/infra/store"
 	"scalingo/internal/router"
 
 	"scalingo/internal/infra/config"
 )
 
+// httpSet provides the HTTP routes and everything behind them, from the configuration and the app context
+var httpSet = wire.NewSet(
+	router.ProvideRouter,
+
+	repositories.ProvideGithub,
+	repositories.ProvideGithubAdapter,
+	repositories.ProvideOffline,
+	repositories.ProvideGitlab,
+	repositories.ProvideGiteas,
+	repositories.ProvideSources,
+	repositories.ProvideEventsPoller,
+	repositories.ProvideEventSource,
+	repositories.ProvideTokenUsage,
+	wire.Bind(new(port.TokenUsageInterface), new(*repositories.SourcesTokenUsage)),
+	cache.ProvideCache,
+	store.ProvideBolt,
+	store.ProvideRepoStore,
+	search.ProvideIndex,
+
+	controller.ProvideRepoHTTPHandler,
+	controller.ProvideResponseCache,
+	controller.ProvideAdminHTTPHandler,
+	service.ProvideRepoService,
+	service.ProvideSnapshotService,
+	wire.Bind(new(port.SnapshotInterface), new(*service.SnapshotService)),
+	snapshot.ProvideArchive,
+	wire.Bind(new(port.RepoInterface), new(*service.RepoService)),
+
+	controller.ProvideEventHTTPHandler,
+	service.ProvideEventService,
+	wire.Bind(new(port.EventInterface), new(*service.EventService)),
+)
+
 func InitializeApp() *App {
 	wire.Build(
 		ProvideApp,
 		context.Background,
 		config.ProvideConfig,
-
-		router.ProvideRouter,
 		controller.ProvideHTTPService,
+		service.ProvideCrawler,
+		httpSet,
+	)
+	return &App{}
+}
 
-		repositories.ProvideGithub,
-		wire.Bind(new(port.GithubInterface), new(*repositories.Github)),
+// InitializeRouter wires the routes with the given configuration, the end-to-end tests serve them against a fake GitHub
+func InitializeRouter(ctx context.Context, config *config.Config) *gin.Engine {
+	wire.Build(httpSet)
+	return &gin.Engine{}
+}
 
-		controller.ProvideRepoHTTPHandler,
-		service.ProvideRepoService,
-		wire.Bind(new(port.RepoInterface), new(*service.RepoService)),
+func InitializeSnapshotCommand() *SnapshotCommand {
+	wire.Build(
+		ProvideSnapshotCommand,
+		ProvideNoSearch,
+		config.ProvideConfig,
+		store.ProvideBolt,
+		store.ProvideRepoStore,
+		snapshot.ProvideArchive,
+		service.ProvideSnapshotService,
 	)
-	return &App{}
+	return &SnapshotCommand{}
 }
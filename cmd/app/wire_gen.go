@@ -8,10 +8,17 @@ package main
 
 import (
 	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/google/wire"
 	"scalingo/internal/controller"
+	"scalingo/internal/core/port"
 	"scalingo/internal/core/service"
+	"scalingo/internal/infra/cache"
 	"scalingo/internal/infra/config"
 	"scalingo/internal/infra/repositories"
+	"scalingo/internal/infra/search"
+	"scalingo/internal/infra/snapshot"
+	"scalingo/internal/infra/store"
 	"scalingo/internal/router"
 )
 
@@ -21,10 +28,72 @@ func InitializeApp() *App {
 	contextContext := context.Background()
 	configConfig := config.ProvideConfig()
 	github := repositories.ProvideGithub(configConfig)
-	repoService := service.ProvideRepoService(configConfig, github)
-	repoHTTPHandler := controller.ProvideRepoHTTPHandler(repoService)
-	engine := router.ProvideRouter(contextContext, repoHTTPHandler, configConfig)
+	offline := repositories.ProvideOffline(configConfig)
+	githubInterface := repositories.ProvideGithubAdapter(configConfig, github, offline)
+	gitlab := repositories.ProvideGitlab(configConfig)
+	v := repositories.ProvideGiteas(configConfig)
+	sources := repositories.ProvideSources(configConfig, githubInterface, gitlab, v)
+	cacheInterface := cache.ProvideCache(configConfig)
+	bolt := store.ProvideBolt(configConfig)
+	repoStoreInterface := store.ProvideRepoStore(bolt)
+	searchIndexInterface := search.ProvideIndex(configConfig, repoStoreInterface, sources)
+	repoService := service.ProvideRepoService(configConfig, githubInterface, sources, cacheInterface, repoStoreInterface, searchIndexInterface)
+	responseCache := controller.ProvideResponseCache(configConfig)
+	repoHTTPHandler := controller.ProvideRepoHTTPHandler(repoService, responseCache)
+	eventsPoller := repositories.ProvideEventsPoller(configConfig, github)
+	eventSourceInterface := repositories.ProvideEventSource(eventsPoller, offline)
+	eventService := service.ProvideEventService(eventSourceInterface)
+	eventHTTPHandler := controller.ProvideEventHTTPHandler(eventService)
+	sourcesTokenUsage := repositories.ProvideTokenUsage(sources)
+	snapshotArchiveInterface := snapshot.ProvideArchive()
+	snapshotService := service.ProvideSnapshotService(repoStoreInterface, searchIndexInterface, snapshotArchiveInterface)
+	adminHTTPHandler := controller.ProvideAdminHTTPHandler(configConfig, sourcesTokenUsage, cacheInterface, responseCache, snapshotService)
+	engine := router.ProvideRouter(contextContext, repoHTTPHandler, eventHTTPHandler, adminHTTPHandler, configConfig)
 	httpService := controller.ProvideHTTPService(contextContext, configConfig, engine)
-	app := ProvideApp(httpService)
+	crawler := service.ProvideCrawler(configConfig, repoService)
+	app := ProvideApp(contextContext, httpService, eventsPoller, bolt, crawler, github)
 	return app
 }
+
+// InitializeRouter wires the routes with the given configuration, the end-to-end tests serve them against a fake GitHub
+func InitializeRouter(ctx context.Context, config2 *config.Config) *gin.Engine {
+	github := repositories.ProvideGithub(config2)
+	offline := repositories.ProvideOffline(config2)
+	githubInterface := repositories.ProvideGithubAdapter(config2, github, offline)
+	gitlab := repositories.ProvideGitlab(config2)
+	v := repositories.ProvideGiteas(config2)
+	sources := repositories.ProvideSources(config2, githubInterface, gitlab, v)
+	cacheInterface := cache.ProvideCache(config2)
+	bolt := store.ProvideBolt(config2)
+	repoStoreInterface := store.ProvideRepoStore(bolt)
+	searchIndexInterface := search.ProvideIndex(config2, repoStoreInterface, sources)
+	repoService := service.ProvideRepoService(config2, githubInterface, sources, cacheInterface, repoStoreInterface, searchIndexInterface)
+	responseCache := controller.ProvideResponseCache(config2)
+	repoHTTPHandler := controller.ProvideRepoHTTPHandler(repoService, responseCache)
+	eventsPoller := repositories.ProvideEventsPoller(config2, github)
+	eventSourceInterface := repositories.ProvideEventSource(eventsPoller, offline)
+	eventService := service.ProvideEventService(eventSourceInterface)
+	eventHTTPHandler := controller.ProvideEventHTTPHandler(eventService)
+	sourcesTokenUsage := repositories.ProvideTokenUsage(sources)
+	snapshotArchiveInterface := snapshot.ProvideArchive()
+	snapshotService := service.ProvideSnapshotService(repoStoreInterface, searchIndexInterface, snapshotArchiveInterface)
+	adminHTTPHandler := controller.ProvideAdminHTTPHandler(config2, sourcesTokenUsage, cacheInterface, responseCache, snapshotService)
+	engine := router.ProvideRouter(ctx, repoHTTPHandler, eventHTTPHandler, adminHTTPHandler, config2)
+	return engine
+}
+
+func InitializeSnapshotCommand() *SnapshotCommand {
+	configConfig := config.ProvideConfig()
+	bolt := store.ProvideBolt(configConfig)
+	repoStoreInterface := store.ProvideRepoStore(bolt)
+	searchIndexInterface := ProvideNoSearch()
+	snapshotArchiveInterface := snapshot.ProvideArchive()
+	snapshotService := service.ProvideSnapshotService(repoStoreInterface, searchIndexInterface, snapshotArchiveInterface)
+	snapshotCommand := ProvideSnapshotCommand(bolt, snapshotService)
+	return snapshotCommand
+}
+
+// wire.go:
+
+// httpSet provides the HTTP routes and everything behind them, from the configuration and the app context
+var httpSet = wire.NewSet(router.ProvideRouter, repositories.ProvideGithub, repositories.ProvideGithubAdapter, repositories.ProvideOffline, repositories.ProvideGitlab, repositories.ProvideGiteas, repositories.ProvideSources, repositories.ProvideEventsPoller, repositories.ProvideEventSource, repositories.ProvideTokenUsage, wire.Bind(new(port.TokenUsageInterface), new(*repositories.SourcesTokenUsage)), cache.ProvideCache, store.ProvideBolt, store.ProvideRepoStore, search.ProvideIndex, controller.ProvideRepoHTTPHandler, controller.ProvideResponseCache, controller.ProvideAdminHTTPHandler, service.ProvideRepoService, service.ProvideSnapshotService, wire.Bind(new(port.SnapshotInterface), new(*service.SnapshotService)), snapshot.ProvideArchive, wire.Bind(new(port.RepoInterface), new(*service.RepoService)), controller.ProvideEventHTTPHandler, service.ProvideEventService, wire.Bind(new(port.EventInterface), new(*service.EventService)))
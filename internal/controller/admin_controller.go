@@ -0,0 +1,170 @@
+package controller
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"scalingo/internal/core/domain"
+	"scalingo/internal/core/port"
+	"scalingo/internal/core/service"
+	conf "scalingo/internal/infra/config"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+const bearerPrefix = "Bearer "
+
+// Query parameters of the snapshot export, the filters of the repositories listing
+var snapshotFilters = []string{"language", "license", "name_contains", "description_contains", "min_size", "max_size"}
+
+func ProvideAdminHTTPHandler(
+	config *conf.Config,
+	tokenUsageInterface port.TokenUsageInterface,
+	cacheInterface port.CacheInterface,
+	responseCache *ResponseCache,
+	snapshotInterface port.SnapshotInterface,
+) *AdminHTTPHandler {
+	return &AdminHTTPHandler{
+		adminToken:          config.AdminToken,
+		tokenUsageInterface: tokenUsageInterface,
+		cacheInterface:      cacheInterface,
+		responseCache:       responseCache,
+		snapshotInterface:   snapshotInterface,
+	}
+}
+
+type AdminHTTPHandler struct {
+	adminToken          string
+	tokenUsageInterface port.TokenUsageInterface
+	cacheInterface      port.CacheInterface
+	responseCache       *ResponseCache
+	snapshotInterface   port.SnapshotInterface
+}
+
+// Authenticate rejects the requests without the admin bearer token, all of them when no token is configured
+func (a *AdminHTTPHandler) Authenticate(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), bearerPrefix)
+	if a.adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(a.adminToken)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, map[string]string{"message": "invalid admin token"})
+	}
+}
+
+func (a *AdminHTTPHandler) TokenUsageController(c *gin.Context) {
+	c.JSON(http.StatusOK, a.tokenUsageInterface.TokenUsage())
+}
+
+// CacheStatsController reports the hits and misses of the enrichment cache
+func (a *AdminHTTPHandler) CacheStatsController(c *gin.Context) {
+	if a.cacheInterface == nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, map[string]string{"message": "cache disabled"})
+		return
+	}
+	c.JSON(http.StatusOK, a.cacheInterface.Stats())
+}
+
+func (a *AdminHTTPHandler) CachePurgeController(c *gin.Context) {
+	if a.cacheInterface == nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, map[string]string{"message": "cache disabled"})
+		return
+	}
+	a.cacheInterface.Purge()
+	c.Status(http.StatusNoContent)
+}
+
+// ResponsesPurgeController drops the cached /repositories answers, the next requests are fetched from the sources
+func (a *AdminHTTPHandler) ResponsesPurgeController(c *gin.Context) {
+	if a.responseCache == nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, map[string]string{"message": "response cache disabled"})
+		return
+	}
+	c.JSON(http.StatusOK, map[string]int{"purged": a.responseCache.Purge()})
+}
+
+// SnapshotExportController streams a snapshot of the stored repositories, of the comma separated sources of the source
+// query parameter or of all of them, filtered like the listing with the other query parameters
+func (a *AdminHTTPHandler) SnapshotExportController(c *gin.Context) {
+	exportInput, err := snapshotExportInput(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "application/zstd")
+	c.Header("Content-Disposition", `attachment; filename="snapshot-`+time.Now().UTC().Format("20060102-150405")+`.jsonl.zst"`)
+	manifest, err := a.snapshotInterface.Export(c.Writer, exportInput)
+	if err != nil {
+		log.Errorf("Snapshot export error: %s", err.Error())
+		if !c.Writer.Written() {
+			c.Header("Content-Type", "")
+			c.Header("Content-Disposition", "")
+			c.AbortWithStatusJSON(snapshotErrorStatus(err), map[string]string{"message": err.Error()})
+		}
+		return
+	}
+	log.Infof("Snapshot of %d repositories exported", manifest.Repositories)
+}
+
+// SnapshotImportController loads the snapshot sent as the request body into the store
+func (a *AdminHTTPHandler) SnapshotImportController(c *gin.Context) {
+	output, err := a.snapshotInterface.Import(c.Request.Body)
+	if err != nil {
+		log.Errorf("Snapshot import error: %s", err.Error())
+		c.AbortWithStatusJSON(snapshotErrorStatus(err), map[string]string{"message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, output)
+}
+
+func snapshotErrorStatus(err error) int {
+	if errors.Is(err, service.ErrIndexDisabled) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+func snapshotExportInput(c *gin.Context) (*domain.SnapshotExportInput, error) {
+	exportInput := &domain.SnapshotExportInput{}
+	if sources := c.Query("source"); sources != "" {
+		for _, source := range strings.Split(sources, ",") {
+			if source = strings.TrimSpace(source); source != "" {
+				exportInput.Sources = append(exportInput.Sources, source)
+			}
+		}
+	}
+
+	filters := make(map[string]any)
+	for _, name := range snapshotFilters {
+		value, ok := c.GetQuery(name)
+		if !ok {
+			continue
+		}
+		if !strings.HasSuffix(name, "_size") {
+			filters[name] = value
+			continue
+		}
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid " + name + ": " + value)
+		}
+		filters[name] = size
+	}
+	if len(filters) == 0 {
+		return exportInput, nil
+	}
+
+	// Validated as the body of a listing
+	serialized, err := jsoniter.Marshal(filters)
+	if err != nil {
+		return nil, err
+	}
+	if exportInput.Filters, err = validateListProjects(serialized); err != nil {
+		return nil, err
+	}
+	return exportInput, nil
+}
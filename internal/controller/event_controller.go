@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"scalingo/internal/core/port"
+	"scalingo/internal/core/service"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+const ndjsonContentType = "application/x-ndjson"
+
+func ProvideEventHTTPHandler(
+	eventInterface port.EventInterface,
+) *EventHTTPHandler {
+	return &EventHTTPHandler{
+		eventInterface: eventInterface,
+	}
+}
+
+type EventHTTPHandler struct {
+	eventInterface port.EventInterface
+}
+
+// EventController lists the buffered public events, or tails them as NDJSON when following
+func (e *EventHTTPHandler) EventController(ctx context.Context, c *gin.Context) {
+	input, err := c.GetRawData()
+	if err != nil {
+		log.Errorf("List events - unable to read input: %#v\n", err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+
+	domainInput, err := validateListEvents(input)
+	if err != nil {
+		log.Errorf("List events - validation error: %#v\n", err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+
+	if !domainInput.Follow {
+		eventList, listErr := e.eventInterface.ListEvents(ctx, domainInput)
+		if listErr != nil {
+			log.Errorf("List events error: %#v\n", listErr)
+			c.AbortWithStatusJSON(eventErrorStatus(listErr), map[string]string{"message": listErr.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, eventList)
+		return
+	}
+
+	// Subscribing before listing the buffer so that no event is missed in between, the stream ends with the client connection
+	streamCtx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	stream, err := e.eventInterface.StreamEvents(streamCtx, domainInput)
+	if err != nil {
+		log.Errorf("Stream events error: %#v\n", err)
+		c.AbortWithStatusJSON(eventErrorStatus(err), map[string]string{"message": err.Error()})
+		return
+	}
+	eventList, err := e.eventInterface.ListEvents(ctx, domainInput)
+	if err != nil {
+		log.Errorf("List events error: %#v\n", err)
+		c.AbortWithStatusJSON(eventErrorStatus(err), map[string]string{"message": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", ndjsonContentType)
+	c.Status(http.StatusOK)
+	encoder := jsoniter.NewEncoder(c.Writer)
+	listed := make(map[string]struct{}, len(eventList))
+	for _, event := range eventList {
+		listed[event.ID] = struct{}{}
+		if err = encoder.Encode(event); err != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		event, ok := <-stream
+		if !ok {
+			return false
+		}
+		if _, ok = listed[event.ID]; ok {
+			return true
+		}
+		return encoder.Encode(event) == nil
+	})
+}
+
+func eventErrorStatus(err error) int {
+	if errors.Is(err, service.ErrEventsDisabled) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadRequest
+}
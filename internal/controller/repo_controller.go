@@ -2,23 +2,36 @@ package controller
 
 import (
 	"net/http"
+	"scalingo/internal/core/domain"
 	"scalingo/internal/core/port"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
 )
 
+const jsonContentType = "application/json; charset=utf-8"
+
+// Same output as gin's c.JSON, the highlights keep their HTML tags escaped
+var responseJSON = jsoniter.ConfigCompatibleWithStandardLibrary
+
 func ProvideRepoHTTPHandler(
 	repoInterface port.RepoInterface,
+	responseCache *ResponseCache,
 ) *RepoHTTPHandler {
 	return &RepoHTTPHandler{
 		repoInterface: repoInterface,
+		responseCache: responseCache,
 	}
 }
 
 type RepoHTTPHandler struct {
 	repoInterface port.RepoInterface
+	responseCache *ResponseCache
 }
 
 func (p *RepoHTTPHandler) RepoController(ctx context.Context, c *gin.Context) {
@@ -36,12 +49,105 @@ func (p *RepoHTTPHandler) RepoController(ctx context.Context, c *gin.Context) {
 		return
 	}
 
-	projectList, err := p.repoInterface.ListRepositories(ctx, domainInput)
+	if p.responseCache == nil {
+		body, status, err := p.fetch(ctx, domainInput)
+		if err != nil {
+			c.AbortWithStatusJSON(status, map[string]string{"message": err.Error()})
+			return
+		}
+		c.Data(http.StatusOK, jsonContentType, body)
+		return
+	}
+
+	key := p.responseCache.Key(domainInput)
+	if !noCache(c) {
+		if response, fresh, found := p.responseCache.Get(key); found {
+			if !fresh {
+				p.revalidate(ctx, key, domainInput)
+			}
+			p.respond(c, response)
+			return
+		}
+	}
+
+	body, status, err := p.fetch(ctx, domainInput)
 	if err != nil {
-		log.Errorf("List projects error: %#v\n", err)
-		c.AbortWithStatusJSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+		c.AbortWithStatusJSON(status, map[string]string{"message": err.Error()})
+		return
+	}
+	p.respond(c, p.responseCache.Set(key, body))
+}
+
+// fetch lists the repositories and serializes them, the status goes along the error
+func (p *RepoHTTPHandler) fetch(ctx context.Context, domainInput *domain.ListRepoInput) ([]byte, int, error) {
+	var output any
+	if domainInput.Source == domain.AllSources {
+		federated, err := p.repoInterface.SearchRepositories(ctx, domainInput)
+		if err != nil {
+			log.Errorf("Search projects error: %#v\n", err)
+			return nil, http.StatusBadGateway, err
+		}
+		output = federated
+	} else {
+		projectList, err := p.repoInterface.ListRepositories(ctx, domainInput)
+		if err != nil {
+			log.Errorf("List projects error: %#v\n", err)
+			return nil, http.StatusBadRequest, err
+		}
+		output = projectList
+	}
+
+	body, err := responseJSON.Marshal(output)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return body, http.StatusOK, nil
+}
+
+// revalidate refreshes the stale answer in the background, once at a time per key
+func (p *RepoHTTPHandler) revalidate(ctx context.Context, key string, domainInput *domain.ListRepoInput) {
+	if !p.responseCache.Refresh(key) {
+		return
+	}
+	go func() {
+		defer p.responseCache.Refreshed(key)
+		body, _, err := p.fetch(ctx, domainInput)
+		if err != nil {
+			return // the stale answer is served until the end of the stale period
+		}
+		p.responseCache.Set(key, body)
+	}()
+}
+
+func (p *RepoHTTPHandler) respond(c *gin.Context, response *cachedResponse) {
+	c.Header("Cache-Control", p.responseCache.CacheControl())
+	c.Header("ETag", response.etag)
+	c.Header("Age", strconv.Itoa(int(time.Since(response.storedAt).Seconds())))
+
+	if matchesETag(c.GetHeader("If-None-Match"), response.etag) {
+		c.Status(http.StatusNotModified)
 		return
 	}
+	c.Data(http.StatusOK, jsonContentType, response.body)
+}
 
-	c.JSON(http.StatusOK, projectList)
+// noCache tells the clients asking for an answer fetched from the sources
+func noCache(c *gin.Context) bool {
+	for _, directive := range strings.Split(c.GetHeader("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		if directive == "no-cache" || directive == "no-store" || directive == "max-age=0" {
+			return true
+		}
+	}
+	return strings.EqualFold(c.GetHeader("Pragma"), "no-cache")
+}
+
+func matchesETag(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
 }
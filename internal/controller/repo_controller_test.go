@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"scalingo/internal/core/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	s "github.com/stretchr/testify/suite"
+	"golang.org/x/net/context"
+)
+
+type RepoControllerSuite struct {
+	s.Suite
+	repoService *mockRepoService
+	cache       *ResponseCache
+	router      *gin.Engine
+}
+
+// mockRepoService counts the listings, every one of them answering a different description
+type mockRepoService struct {
+	calls atomic.Int32
+}
+
+func (m *mockRepoService) ListRepositories(_ context.Context, _ *domain.ListRepoInput) ([]*domain.ListRepoOutput, error) {
+	call := m.calls.Add(1)
+	return []*domain.ListRepoOutput{{FullName: "john_doe/repo_one", Description: strings.Repeat("a", int(call))}}, nil
+}
+
+func (m *mockRepoService) SearchRepositories(_ context.Context, _ *domain.ListRepoInput) (*domain.FederatedRepoOutput, error) {
+	return &domain.FederatedRepoOutput{}, nil
+}
+
+func (suite *RepoControllerSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	suite.repoService = &mockRepoService{}
+	suite.cache = NewResponseCache(time.Minute, time.Minute, 10)
+	handler := ProvideRepoHTTPHandler(suite.repoService, suite.cache)
+	suite.router = gin.New()
+	suite.router.GET("/repositories", func(c *gin.Context) { handler.RepoController(context.Background(), c) })
+}
+
+func (suite *RepoControllerSuite) request(body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/repositories", strings.NewReader(body))
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	recorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func (suite *RepoControllerSuite) TestRepoController_Cached() {
+	first := suite.request(`{"language":"Go"}`, nil)
+	second := suite.request(`{"language":" go"}`, nil)
+
+	assert.Equal(suite.T(), http.StatusOK, second.Code)
+	assert.Equal(suite.T(), int32(1), suite.repoService.calls.Load())
+	assert.Equal(suite.T(), first.Body.String(), second.Body.String())
+	assert.Equal(suite.T(), "public, max-age=60, stale-while-revalidate=60", second.Header().Get("Cache-Control"))
+	assert.Equal(suite.T(), "0", second.Header().Get("Age"))
+	assert.NotEmpty(suite.T(), second.Header().Get("ETag"))
+}
+
+func (suite *RepoControllerSuite) TestRepoController_NotModified() {
+	etag := suite.request(`{}`, nil).Header().Get("ETag")
+
+	recorder := suite.request(`{}`, map[string]string{"If-None-Match": etag})
+
+	assert.Equal(suite.T(), http.StatusNotModified, recorder.Code)
+	assert.Empty(suite.T(), recorder.Body.String())
+}
+
+func (suite *RepoControllerSuite) TestRepoController_NoCache() {
+	etag := suite.request(`{}`, nil).Header().Get("ETag")
+
+	recorder := suite.request(`{}`, map[string]string{"Cache-Control": "no-cache"})
+
+	assert.Equal(suite.T(), int32(2), suite.repoService.calls.Load())
+	assert.NotEqual(suite.T(), etag, recorder.Header().Get("ETag"))
+	assert.Equal(suite.T(), recorder.Header().Get("ETag"), suite.request(`{}`, nil).Header().Get("ETag"))
+}
+
+func (suite *RepoControllerSuite) TestRepoController_StaleWhileRevalidate() {
+	suite.request(`{}`, nil)
+	key := suite.cache.Key(&domain.ListRepoInput{})
+	suite.cache.entries[key].Value.(*cachedResponse).storedAt = time.Now().Add(-90 * time.Second)
+
+	recorder := suite.request(`{}`, nil)
+
+	assert.Equal(suite.T(), http.StatusOK, recorder.Code)
+	assert.Equal(suite.T(), "90", recorder.Header().Get("Age"))
+	assert.Eventually(suite.T(), func() bool {
+		response, fresh, _ := suite.cache.Get(key)
+		return fresh && response.etag != recorder.Header().Get("ETag")
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(suite.T(), int32(2), suite.repoService.calls.Load())
+}
+
+func (suite *RepoControllerSuite) TestRepoController_Expired() {
+	suite.request(`{}`, nil)
+	key := suite.cache.Key(&domain.ListRepoInput{})
+	suite.cache.entries[key].Value.(*cachedResponse).storedAt = time.Now().Add(-3 * time.Minute)
+
+	recorder := suite.request(`{}`, nil)
+
+	assert.Equal(suite.T(), "0", recorder.Header().Get("Age"))
+	assert.Equal(suite.T(), int32(2), suite.repoService.calls.Load())
+}
+
+func (suite *RepoControllerSuite) TestResponseCache_Purge() {
+	suite.request(`{}`, nil)
+
+	assert.Equal(suite.T(), 1, suite.cache.Purge())
+	suite.request(`{}`, nil)
+	assert.Equal(suite.T(), int32(2), suite.repoService.calls.Load())
+}
+
+func TestRepoControllerSuite(t *testing.T) {
+	s.Run(t, new(RepoControllerSuite))
+}
@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"container/list"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+
+	"scalingo/internal/core/domain"
+	"scalingo/internal/core/port"
+	conf "scalingo/internal/infra/config"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ResponseCache keeps the serialized /repositories answers by normalized input. An answer is fresh during the TTL,
+// then served stale while it is refreshed in the background until the stale period is over
+type ResponseCache struct {
+	sync.Mutex
+	ttl        time.Duration
+	stale      time.Duration
+	capacity   int
+	entries    map[string]*list.Element
+	order      *list.List // most recently stored first
+	refreshing map[string]struct{}
+}
+
+type cachedResponse struct {
+	key      string
+	body     []byte
+	etag     string
+	storedAt time.Time
+}
+
+// ProvideResponseCache builds the response cache, nil when RESPONSE_CACHE_TTL is zero
+func ProvideResponseCache(config *conf.Config) *ResponseCache {
+	if config.ResponseCacheTTL <= 0 {
+		return nil
+	}
+	return NewResponseCache(config.ResponseCacheTTL, config.ResponseCacheStale, config.ResponseCacheSize)
+}
+
+func NewResponseCache(ttl, stale time.Duration, capacity int) *ResponseCache {
+	return &ResponseCache{
+		ttl:        ttl,
+		stale:      max(stale, 0),
+		capacity:   max(capacity, 1),
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		refreshing: make(map[string]struct{}),
+	}
+}
+
+// Key identifies the inputs selecting the same repositories
+func (r *ResponseCache) Key(input *domain.ListRepoInput) string {
+	key, err := jsoniter.MarshalToString(input.Normalize(port.DefaultSource))
+	if err != nil {
+		return ""
+	}
+	return key
+}
+
+// Get returns the cached answer, fresh is false once the TTL is over, nothing is returned after the stale period
+func (r *ResponseCache) Get(key string) (response *cachedResponse, fresh, found bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	element, ok := r.entries[key]
+	if !ok {
+		return nil, false, false
+	}
+	response = element.Value.(*cachedResponse)
+	age := time.Since(response.storedAt)
+	if age >= r.ttl+r.stale {
+		r.order.Remove(element)
+		delete(r.entries, key)
+		return nil, false, false
+	}
+	return response, age < r.ttl, true
+}
+
+func (r *ResponseCache) Set(key string, body []byte) *cachedResponse {
+	hash := fnv.New64a()
+	_, _ = hash.Write(body)
+	response := &cachedResponse{
+		key:      key,
+		body:     body,
+		etag:     `"` + strconv.FormatUint(hash.Sum64(), 16) + `"`,
+		storedAt: time.Now(),
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	if element, ok := r.entries[key]; ok {
+		r.order.Remove(element)
+	}
+	r.entries[key] = r.order.PushFront(response)
+	for r.order.Len() > r.capacity {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.entries, oldest.Value.(*cachedResponse).key)
+	}
+	return response
+}
+
+// Refresh reserves the refresh of the key, false when another one is already running
+func (r *ResponseCache) Refresh(key string) bool {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.refreshing[key]; ok {
+		return false
+	}
+	r.refreshing[key] = struct{}{}
+	return true
+}
+
+func (r *ResponseCache) Refreshed(key string) {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.refreshing, key)
+}
+
+func (r *ResponseCache) Purge() int {
+	r.Lock()
+	defer r.Unlock()
+
+	purged := r.order.Len()
+	r.entries = make(map[string]*list.Element)
+	r.order.Init()
+	return purged
+}
+
+// CacheControl is the header sent along the cached answers
+func (r *ResponseCache) CacheControl() string {
+	return "public, max-age=" + strconv.Itoa(int(r.ttl.Seconds())) +
+		", stale-while-revalidate=" + strconv.Itoa(int(r.stale.Seconds()))
+}
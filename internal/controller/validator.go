@@ -1,7 +1,9 @@
 package controller
 
 import (
+	"bytes"
 	"errors"
+	"path"
 	"scalingo/internal/core/domain"
 
 	jsoniter "github.com/json-iterator/go"
@@ -16,6 +18,9 @@ var validFields = map[string]bool{
 	"description_contains": true,
 	"min_size":             true,
 	"max_size":             true,
+	"source":               true,
+	"mode":                 true,
+	"text":                 true,
 }
 
 func validateListProjects(domainInput []byte) (*domain.ListRepoInput, error) {
@@ -52,3 +57,47 @@ func validateListProjects(domainInput []byte) (*domain.ListRepoInput, error) {
 	}
 	return dInput, nil
 }
+
+var validEventFields = map[string]bool{
+	"types":    true,
+	"actor":    true,
+	"repo":     true,
+	"ref_type": true,
+	"limit":    true,
+	"follow":   true,
+}
+
+func validateListEvents(eventsInput []byte) (*domain.ListEventsInput, error) {
+	dInput := &domain.ListEventsInput{}
+	if len(bytes.TrimSpace(eventsInput)) == 0 {
+		return dInput, nil
+	}
+
+	var input map[string]any
+	if err := jsoniter.Unmarshal(eventsInput, &input); err != nil {
+		return nil, errors.New("List events - unable to deserialize: " + err.Error())
+	}
+
+	for key := range input {
+		if !validEventFields[key] {
+			return nil, errors.New("invalid field: " + key)
+		}
+	}
+
+	err := jsoniter.Unmarshal(eventsInput, &dInput)
+	if err != nil {
+		return nil, errors.New("List events - unable to deserialize: " + err.Error())
+	}
+
+	validate := validator.New()
+
+	err = validate.Struct(dInput)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = path.Match(dInput.Repo, ""); err != nil {
+		return nil, errors.New("validation failed: invalid repo pattern: " + err.Error())
+	}
+	return dInput, nil
+}
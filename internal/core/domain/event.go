@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+type ListEventsInput struct {
+	Types   []string `json:"types" validate:"omitempty,dive,oneof=Push Fork Watch Release Create"`
+	Actor   string   `json:"actor" validate:"omitempty"`
+	Repo    string   `json:"repo" validate:"omitempty"`
+	RefType string   `json:"ref_type" validate:"omitempty,oneof=repository branch tag"`
+	Limit   int      `json:"limit" validate:"omitempty,min=1"`
+	Follow  bool     `json:"follow"`
+}
+
+type Event struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Actor     string    `json:"actor"`
+	Repo      string    `json:"repo"`
+	Action    string    `json:"action,omitempty"`
+	Ref       string    `json:"ref,omitempty"`
+	RefType   string    `json:"ref_type,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -1,5 +1,20 @@
 package domain
 
+import (
+	"maps"
+	"strings"
+	"time"
+)
+
+const (
+	// AllSources fans the listing out across every configured source
+	AllSources = "all"
+
+	// LiveMode scans the source on every request, IndexMode answers from the repositories indexed by the crawler
+	LiveMode  = "live"
+	IndexMode = "index"
+)
+
 type ListRepoInput struct {
 	Language            string `json:"language" validate:"omitempty"`
 	License             string `json:"license" validate:"omitempty"`
@@ -7,21 +22,100 @@ type ListRepoInput struct {
 	DescriptionContains string `json:"description_contains" validate:"omitempty"`
 	MinSize             int64  `json:"min_size" validate:"omitempty,min=1"`
 	MaxSize             int64  `json:"max_size" validate:"omitempty,min=1"`
+	Source              string `json:"source" validate:"omitempty"`
+	Mode                string `json:"mode" validate:"omitempty,oneof=live index"`
+	Text                string `json:"text" validate:"omitempty"`
+}
+
+// Normalize gives the same input to the requests selecting the same repositories, the filters being case insensitive
+func (i *ListRepoInput) Normalize(defaultSource string) *ListRepoInput {
+	normalized := *i
+	normalized.Language = strings.ToLower(strings.TrimSpace(i.Language))
+	normalized.License = strings.ToLower(strings.TrimSpace(i.License))
+	normalized.NameContains = strings.ToLower(i.NameContains)
+	normalized.DescriptionContains = strings.ToLower(i.DescriptionContains)
+	normalized.Text = strings.ToLower(strings.TrimSpace(i.Text))
+	if normalized.Source == "" {
+		normalized.Source = defaultSource
+	}
+	return &normalized
 }
 
 type ListRepoOutput struct {
-	FullName    string         `json:"full_name"`
-	Owner       string         `json:"owner"`
-	Repository  string         `json:"repository"`
-	License     string         `json:"license"`
-	Description string         `json:"description"`
-	Languages   map[string]int `json:"languages"`
+	Source           string         `json:"source"`
+	FullName         string         `json:"full_name"`
+	PreviousFullName string         `json:"previous_full_name,omitempty"`
+	Owner            string         `json:"owner"`
+	Repository       string         `json:"repository"`
+	License          string         `json:"license"`
+	Description      string         `json:"description"`
+	Languages        map[string]int `json:"languages"`
+	LanguagesUnit    string         `json:"languages_unit,omitempty"` // empty for bytes
+
+	// Ranking of the full-text searches
+	Score      float64           `json:"score,omitempty"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+// FederatedRepoOutput merges the repositories of every source, the sources which failed are reported by name
+type FederatedRepoOutput struct {
+	Repositories []*ListRepoOutput `json:"repositories"`
+	Errors       map[string]string `json:"errors,omitempty"`
 }
 
+// StoredRepository is an enriched repository saved by the persistent store, along with the time it was fetched at
+type StoredRepository struct {
+	Source     string          `json:"source"`
+	ID         int             `json:"id"`
+	FetchedAt  time.Time       `json:"fetched_at"`
+	Repository *ListRepoOutput `json:"repository"`
+}
+
+// Clone copies the repository along with its maps, a coalesced result is handed to each caller as its own copy
+func (l *ListRepoOutput) Clone() *ListRepoOutput {
+	clone := *l
+	clone.Languages = maps.Clone(l.Languages)
+	clone.Highlights = maps.Clone(l.Highlights)
+	return &clone
+}
+
+// CloneRepositories copies the list and every repository of it
+func CloneRepositories(repositories []*ListRepoOutput) []*ListRepoOutput {
+	if repositories == nil {
+		return nil
+	}
+	clones := make([]*ListRepoOutput, len(repositories))
+	for i, repository := range repositories {
+		clones[i] = repository.Clone()
+	}
+	return clones
+}
+
+// Clone copies the repositories and the errors of the federated listing
+func (f *FederatedRepoOutput) Clone() *FederatedRepoOutput {
+	return &FederatedRepoOutput{
+		Repositories: CloneRepositories(f.Repositories),
+		Errors:       maps.Clone(f.Errors),
+	}
+}
+
+// RepoSize sums the bytes of the languages, -1 when the languages aren't reported in bytes and the size is unknown
 func (l *ListRepoOutput) RepoSize() int64 {
+	if l.LanguagesUnit != "" {
+		return -1
+	}
 	totalSize := int64(0)
 	for _, currentLanguageSize := range l.Languages {
 		totalSize += int64(currentLanguageSize)
 	}
 	return totalSize
 }
+
+// SearchHit is a repository matching a full-text query, Highlights holds the matching fields with the matched words in <em> tags
+type SearchHit struct {
+	Source     string
+	ID         int
+	Score      float64
+	Repository *ListRepoOutput
+	Highlights map[string]string
+}
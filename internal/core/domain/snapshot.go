@@ -0,0 +1,51 @@
+package domain
+
+import "time"
+
+// SnapshotVersion is the version of the archive format written by the export, the import rejects newer ones
+const SnapshotVersion = 1
+
+// SnapshotHeader opens a snapshot archive, it describes how the repositories were selected
+type SnapshotHeader struct {
+	Version   int                        `json:"version"`
+	CreatedAt time.Time                  `json:"created_at"`
+	Sources   map[string]*SnapshotSource `json:"sources"`
+	Filters   *ListRepoInput             `json:"filters,omitempty"`
+}
+
+// SnapshotSource is the crawler cursor of a source at the time of the export
+type SnapshotSource struct {
+	Checkpoint int `json:"checkpoint,omitempty"`
+}
+
+// SnapshotManifest closes a snapshot archive, the import checks the repositories of every source against it
+type SnapshotManifest struct {
+	Repositories int                          `json:"repositories"`
+	Sources      map[string]*SnapshotChecksum `json:"sources"`
+}
+
+// SnapshotChecksum is the scanned ID range of a source along with the SHA-256 of its repository lines
+type SnapshotChecksum struct {
+	Repositories int    `json:"repositories"`
+	MinID        int    `json:"min_id"`
+	MaxID        int    `json:"max_id"`
+	SHA256       string `json:"sha256"`
+}
+
+// Snapshot is a decoded and verified archive
+type Snapshot struct {
+	Header       *SnapshotHeader
+	Repositories []*StoredRepository
+	Manifest     *SnapshotManifest
+}
+
+// SnapshotExportInput selects the exported repositories, those of every stored source when Sources is empty
+type SnapshotExportInput struct {
+	Sources []string
+	Filters *ListRepoInput
+}
+
+type SnapshotImportOutput struct {
+	Repositories int                          `json:"repositories"`
+	Sources      map[string]*SnapshotChecksum `json:"sources"`
+}
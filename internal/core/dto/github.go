@@ -1,11 +1,14 @@
 package dto
 
+import "time"
+
 type Owner struct {
 	Login string `json:"login"`
 }
 
 type LatestCreatedRepo struct {
 	ID           int    `json:"id"`
+	NodeID       string `json:"node_id"`
 	Name         string `json:"name"`
 	FullName     string `json:"full_name"`
 	Owner        *Owner `json:"owner"`
@@ -14,3 +17,56 @@ type LatestCreatedRepo struct {
 	URL          string `json:"url"`
 	Description  string `json:"description"`
 }
+
+// RepositoryDetails describes the current identity of a repository, which differs from the listed one
+// when the repository has been renamed or transferred since
+// BasisPointsUnit is the unit of the languages of the sources reporting shares instead of bytes, hundredths of a percent
+const BasisPointsUnit = "basis_points"
+
+// RepositoryDetails carries the unit of the languages of the repository, empty for bytes
+type RepositoryDetails struct {
+	FullName      string `json:"full_name"`
+	HTMLURL       string `json:"html_url"`
+	License       string `json:"license"`
+	LanguagesUnit string `json:"languages_unit,omitempty"`
+}
+
+type RepositoryEnrichment struct {
+	FullName      string         `json:"full_name"`
+	HTMLURL       string         `json:"html_url"`
+	License       string         `json:"license"`
+	Languages     map[string]int `json:"languages"`
+	LanguagesUnit string         `json:"languages_unit,omitempty"`
+}
+
+type TokenUsage struct {
+	Source         string `json:"source"`
+	ID             string `json:"id"`
+	Limit          int    `json:"limit"`
+	Remaining      int    `json:"remaining"`
+	Requests       int    `json:"requests"`
+	Reset          string `json:"reset,omitempty"`
+	Active         bool   `json:"active"`
+	DisabledReason string `json:"disabled_reason,omitempty"`
+}
+
+// Event is a public GitHub event, reduced to the fields used to follow the activity
+type Event struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Actor     string    `json:"actor"`
+	RepoID    int       `json:"repo_id"`
+	RepoName  string    `json:"repo_name"`
+	Action    string    `json:"action,omitempty"`
+	Ref       string    `json:"ref,omitempty"`
+	RefType   string    `json:"ref_type,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CacheStats struct {
+	Entries   int   `json:"entries"`
+	Capacity  int   `json:"capacity"`
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+}
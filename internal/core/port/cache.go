@@ -0,0 +1,16 @@
+package port
+
+import (
+	"time"
+
+	"scalingo/internal/core/dto"
+)
+
+// CacheInterface stores serialized values for a limited time, a nil CacheInterface disables the caching
+type CacheInterface interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration)
+	Delete(key string)
+	Purge()
+	Stats() *dto.CacheStats
+}
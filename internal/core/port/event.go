@@ -0,0 +1,18 @@
+package port
+
+import (
+	"golang.org/x/net/context"
+
+	"scalingo/internal/core/domain"
+	"scalingo/internal/core/dto"
+)
+
+type EventSourceInterface interface {
+	Recent() []*dto.Event
+	Subscribe(buffer int) (events <-chan *dto.Event, unsubscribe func())
+}
+
+type EventInterface interface {
+	ListEvents(ctx context.Context, eventsInput *domain.ListEventsInput) ([]*domain.Event, error)
+	StreamEvents(ctx context.Context, eventsInput *domain.ListEventsInput) (<-chan *domain.Event, error)
+}
@@ -2,9 +2,27 @@ package port
 
 import "scalingo/internal/core/dto"
 
+// DefaultSource is the name of the github.com source, used when a request doesn't select one
+const DefaultSource = "github"
+
 type GithubInterface interface {
 	GetLatestRepoID() (int, error)
-	GetRepositories(id int) ([]*dto.LatestCreatedRepo, error)
+	// GetRepositories lists the page of the repositories created after since, forks excluded, along with the since
+	// of the next page, the highest listed ID forks included, which is since itself once nothing newer is listed
+	GetRepositories(since int) (repos []*dto.LatestCreatedRepo, next int, err error)
 	GetRepositoryLanguages(fullURL string) (map[string]int, error)
-	GetRepositorySPDX(fullURL string) (string, error)
+	GetRepository(fullURL string) (*dto.RepositoryDetails, error)
+}
+
+// Sources indexes the configured repository sources by the name the requests select them with
+type Sources map[string]GithubInterface
+
+// GithubBatchInterface is implemented by the sources able to enrich a whole page of repositories at once,
+// the returned map is indexed by repository ID and may miss the repositories the source couldn't resolve
+type GithubBatchInterface interface {
+	EnrichRepositories(repos []*dto.LatestCreatedRepo) (map[int]*dto.RepositoryEnrichment, error)
+}
+
+type TokenUsageInterface interface {
+	TokenUsage() []*dto.TokenUsage
 }
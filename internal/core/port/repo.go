@@ -8,4 +8,5 @@ import (
 
 type RepoInterface interface {
 	ListRepositories(ctx context.Context, repoInput *domain.ListRepoInput) ([]*domain.ListRepoOutput, error)
+	SearchRepositories(ctx context.Context, repoInput *domain.ListRepoInput) (*domain.FederatedRepoOutput, error)
 }
@@ -0,0 +1,11 @@
+package port
+
+import "scalingo/internal/core/domain"
+
+// SearchIndexInterface is the full-text index of the enriched repositories, a nil SearchIndexInterface disables the text search
+type SearchIndexInterface interface {
+	Index(source string, id int, repository *domain.ListRepoOutput)
+	// Search returns up to limit repositories of the source matching the text and accepted by the filter, the best ranked
+	// first, the repositories of every source when the source is empty
+	Search(text, source string, limit int, accept func(repository *domain.ListRepoOutput) bool) []*domain.SearchHit
+}
@@ -0,0 +1,28 @@
+package port
+
+import (
+	"io"
+
+	"scalingo/internal/core/domain"
+)
+
+// SnapshotArchiveInterface encodes and decodes the snapshot archives of the stored repositories
+type SnapshotArchiveInterface interface {
+	NewWriter(w io.Writer, header *domain.SnapshotHeader) (SnapshotWriterInterface, error)
+	// Read decodes the whole archive and verifies it against its manifest
+	Read(r io.Reader) (*domain.Snapshot, error)
+	// Stream hands the repositories to fn as they are decoded, the archive is only verified against its manifest
+	// once read, the returned snapshot holds the header and the manifest
+	Stream(r io.Reader, fn func(repository *domain.StoredRepository) error) (*domain.Snapshot, error)
+}
+
+type SnapshotWriterInterface interface {
+	Write(repository *domain.StoredRepository) error
+	// Close writes the manifest of the written repositories and flushes the archive
+	Close() (*domain.SnapshotManifest, error)
+}
+
+type SnapshotInterface interface {
+	Export(w io.Writer, exportInput *domain.SnapshotExportInput) (*domain.SnapshotManifest, error)
+	Import(r io.Reader) (*domain.SnapshotImportOutput, error)
+}
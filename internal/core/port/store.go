@@ -0,0 +1,27 @@
+package port
+
+import (
+	"time"
+
+	"scalingo/internal/core/domain"
+)
+
+// RepoStoreInterface persists the enriched repositories across restarts, a nil RepoStoreInterface disables the persistence
+type RepoStoreInterface interface {
+	// Get returns the repository of the source unless it is missing or older than the retention period
+	Get(source string, id int) (*domain.StoredRepository, bool, error)
+	Save(repository *domain.StoredRepository) error
+	// Batch saves the repositories fn hands to save in a single transaction, none of them when fn fails
+	Batch(fn func(save func(repository *domain.StoredRepository) error) error) error
+	// List walks the repositories of the source from the newest to the oldest, until fn returns false
+	List(source string, fn func(repository *domain.StoredRepository) bool) error
+	// Sources returns the names of the sources having stored repositories, sorted
+	Sources() ([]string, error)
+	// Checkpoint returns the cursor of the crawler of the source, false when it never ran
+	Checkpoint(source string) (int, bool, error)
+	SetCheckpoint(source string, id int) error
+	// Prune deletes the repositories fetched before the given time and returns their number
+	Prune(before time.Time) (int, error)
+	// Compact reclaims the space left by the deleted repositories
+	Compact() error
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"time"
+
+	"scalingo/internal/core/dto"
+	"scalingo/internal/core/port"
+	conf "scalingo/internal/infra/config"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	languagesCacheKind  = "languages:"
+	repositoryCacheKind = "repository:"
+)
+
+// cachedSource decorates a source with the enrichment cache, the languages and the repository details are keyed by URL
+type cachedSource struct {
+	port.GithubInterface
+	cache      port.CacheInterface
+	languages  time.Duration
+	repository time.Duration
+	negative   time.Duration
+}
+
+// cachedBatchSource keeps the batch enrichment of the decorated source, only the repositories missing from the cache are enriched
+type cachedBatchSource struct {
+	*cachedSource
+	batch port.GithubBatchInterface
+}
+
+// withCache decorates the source when the caching is enabled
+func withCache(config *conf.Config, github port.GithubInterface, cache port.CacheInterface) port.GithubInterface {
+	if cache == nil || github == nil {
+		return github
+	}
+
+	cached := &cachedSource{
+		GithubInterface: github,
+		cache:           cache,
+		languages:       config.CacheLanguagesTTL,
+		repository:      config.CacheLicenseTTL,
+		negative:        config.CacheNegativeTTL,
+	}
+	if batch, ok := github.(port.GithubBatchInterface); ok {
+		return &cachedBatchSource{cachedSource: cached, batch: batch}
+	}
+	return cached
+}
+
+func (c *cachedSource) GetRepositoryLanguages(fullURL string) (map[string]int, error) {
+	var languages map[string]int
+	if c.lookup(languagesCacheKind+fullURL, &languages) {
+		return languages, nil
+	}
+
+	languages, err := c.GithubInterface.GetRepositoryLanguages(fullURL)
+	if err != nil {
+		return languages, err
+	}
+	c.storeLanguages(fullURL, languages)
+	return languages, nil
+}
+
+func (c *cachedSource) GetRepository(fullURL string) (*dto.RepositoryDetails, error) {
+	details := &dto.RepositoryDetails{}
+	if c.lookup(repositoryCacheKind+fullURL, details) {
+		return details, nil
+	}
+
+	details, err := c.GithubInterface.GetRepository(fullURL)
+	if err != nil {
+		return details, err
+	}
+	c.storeRepository(fullURL, details)
+	return details, nil
+}
+
+func (c *cachedBatchSource) EnrichRepositories(repos []*dto.LatestCreatedRepo) (map[int]*dto.RepositoryEnrichment, error) {
+	enrichments := make(map[int]*dto.RepositoryEnrichment, len(repos))
+	missing := make([]*dto.LatestCreatedRepo, 0, len(repos))
+	for _, repository := range repos {
+		var languages map[string]int
+		details := &dto.RepositoryDetails{}
+		if !c.lookup(languagesCacheKind+repository.LanguagesURL, &languages) || !c.lookup(repositoryCacheKind+repository.URL, details) {
+			missing = append(missing, repository)
+			continue
+		}
+		enrichments[repository.ID] = &dto.RepositoryEnrichment{
+			FullName:  details.FullName,
+			HTMLURL:   details.HTMLURL,
+			License:   details.License,
+			Languages: languages,
+		}
+	}
+	if len(missing) == 0 {
+		return enrichments, nil
+	}
+
+	enriched, err := c.batch.EnrichRepositories(missing)
+	if err != nil {
+		return nil, err
+	}
+	for _, repository := range missing {
+		enrichment, ok := enriched[repository.ID]
+		if !ok { // left to the per repository enrichment
+			continue
+		}
+		enrichments[repository.ID] = enrichment
+		c.storeLanguages(repository.LanguagesURL, enrichment.Languages)
+		c.storeRepository(repository.URL, &dto.RepositoryDetails{
+			FullName: enrichment.FullName,
+			HTMLURL:  enrichment.HTMLURL,
+			License:  enrichment.License,
+		})
+	}
+	return enrichments, nil
+}
+
+// storeLanguages keeps the languages, the empty ones of the missing or empty repositories only for the negative TTL
+func (c *cachedSource) storeLanguages(fullURL string, languages map[string]int) {
+	ttl := c.languages
+	if len(languages) == 0 {
+		ttl = c.negative
+	}
+	c.store(languagesCacheKind+fullURL, languages, ttl)
+}
+
+// storeRepository keeps the details, the adapters answer the missing repositories without a full name
+func (c *cachedSource) storeRepository(fullURL string, details *dto.RepositoryDetails) {
+	ttl := c.repository
+	if details.FullName == "" {
+		ttl = c.negative
+	}
+	c.store(repositoryCacheKind+fullURL, details, ttl)
+}
+
+func (c *cachedSource) lookup(key string, value any) bool {
+	cached, ok := c.cache.Get(key)
+	return ok && jsoniter.Unmarshal(cached, value) == nil
+}
+
+func (c *cachedSource) store(key string, value any, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	if serialized, err := jsoniter.Marshal(value); err == nil {
+		c.cache.Set(key, serialized, ttl)
+	}
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"scalingo/internal/core/domain"
+	"scalingo/internal/core/dto"
+	"scalingo/internal/infra/cache"
+	"scalingo/internal/infra/config"
+
+	"github.com/stretchr/testify/assert"
+	s "github.com/stretchr/testify/suite"
+)
+
+type CachedSourceSuite struct {
+	s.Suite
+	cache  *cache.Memory
+	config *config.Config
+}
+
+// countingGithub counts the enrichment requests reaching the source
+type countingGithub struct {
+	mockBatchGithub
+	languages  int
+	repository int
+	batched    []int
+}
+
+func (m *countingGithub) GetRepositoryLanguages(fullURL string) (map[string]int, error) {
+	m.languages++
+	return m.mockBatchGithub.GetRepositoryLanguages(fullURL)
+}
+
+func (m *countingGithub) GetRepository(fullURL string) (*dto.RepositoryDetails, error) {
+	m.repository++
+	return m.mockBatchGithub.GetRepository(fullURL)
+}
+
+func (m *countingGithub) EnrichRepositories(repos []*dto.LatestCreatedRepo) (map[int]*dto.RepositoryEnrichment, error) {
+	m.batched = append(m.batched, len(repos))
+	return m.mockBatchGithub.EnrichRepositories(repos)
+}
+
+func (suite *CachedSourceSuite) SetupTest() {
+	suite.cache = cache.NewMemory(100)
+	suite.config = &config.Config{
+		OutputSize:        4,
+		CacheLanguagesTTL: time.Hour,
+		CacheLicenseTTL:   time.Hour,
+		CacheNegativeTTL:  time.Minute,
+	}
+}
+
+func (suite *CachedSourceSuite) TearDownTest() {}
+
+func (suite *CachedSourceSuite) TestListRepositories_CachedEnrichment() {
+	github := &countingGithub{}
+	repoService := ProvideRepoService(suite.config, github, nil, suite.cache, nil, nil)
+
+	first, err := repoService.ListRepositories(context.Background(), &domain.ListRepoInput{})
+	assert.NoError(suite.T(), err)
+	second, err := repoService.ListRepositories(context.Background(), &domain.ListRepoInput{})
+	assert.NoError(suite.T(), err)
+
+	assert.ElementsMatch(suite.T(), first, second)
+	// The whole page is batched once, the last repository is enriched once by the single requests
+	assert.Equal(suite.T(), []int{4}, github.batched)
+	assert.Equal(suite.T(), 1, github.languages)
+	assert.Equal(suite.T(), 1, github.repository)
+	assert.Equal(suite.T(), int64(8), suite.cache.Stats().Hits)
+}
+
+func (suite *CachedSourceSuite) TestNegativeCaching() {
+	github := &countingGithub{}
+	cached := withCache(suite.config, github, suite.cache)
+
+	for i := 0; i < 2; i++ {
+		languages, err := cached.GetRepositoryLanguages("https://api.github.com/repos/gone/gone/languages")
+		assert.NoError(suite.T(), err)
+		assert.Empty(suite.T(), languages)
+	}
+
+	assert.Equal(suite.T(), 1, github.languages)
+	suite.cache.Purge()
+	_, _ = cached.GetRepositoryLanguages("https://api.github.com/repos/gone/gone/languages")
+	assert.Equal(suite.T(), 2, github.languages)
+}
+
+func (suite *CachedSourceSuite) TestDisabled() {
+	github := &countingGithub{}
+
+	assert.Equal(suite.T(), github, withCache(suite.config, github, nil))
+}
+
+func TestCachedSourceSuite(t *testing.T) {
+	s.Run(t, new(CachedSourceSuite))
+}
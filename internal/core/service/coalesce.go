@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+
+	"scalingo/internal/core/domain"
+	"scalingo/internal/core/port"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// coalesce runs the listing once for the concurrent calls with the same normalized input, they share its result.
+// The shared listing is not cancelled with the caller who started it, each caller stops waiting at its own deadline
+func (p *RepoService) coalesce(
+	ctx context.Context,
+	kind string,
+	repoInput *domain.ListRepoInput,
+	fn func(ctx context.Context, repoInput *domain.ListRepoInput) (any, error),
+) (any, error) {
+	normalized := repoInput.Normalize(port.DefaultSource)
+	if p.inflight == nil {
+		return fn(ctx, normalized)
+	}
+
+	key, err := jsoniter.MarshalToString(normalized)
+	if err != nil {
+		return fn(ctx, normalized)
+	}
+	shared := context.WithoutCancel(ctx)
+	return p.inflight.DoContext(ctx, kind+key, func() (any, error) {
+		return fn(shared, normalized)
+	})
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"scalingo/internal/core/dto"
+	"scalingo/internal/core/port"
+	conf "scalingo/internal/infra/config"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// crawlerMaxAttempts bounds the enrichments of a repository, the crawler gives up on it past them so the cursor moves on
+const crawlerMaxAttempts = 3
+
+var (
+	ErrIndexDisabled  = errors.New("no repository index, STORE_PATH is not configured")
+	ErrSearchDisabled = errors.New("text search disabled, SEARCH_ENABLED is false")
+)
+
+// Crawler follows the repository ID head of the sources in the background, every listed repository is enriched
+// and saved to the store, the index /repositories answers from, along with the cursor the crawl resumes from
+type Crawler struct {
+	repoService *RepoService
+	sources     []string
+	interval    time.Duration
+
+	// Failed enrichments by source and repository ID
+	attemptsMu sync.Mutex
+	attempts   map[string]map[int]int
+
+	cancel context.CancelFunc
+	done   sync.WaitGroup
+}
+
+// ProvideCrawler returns the crawler of the configured sources, nil when the crawling is disabled
+func ProvideCrawler(config *conf.Config, repoService *RepoService) *Crawler {
+	if !config.CrawlerEnabled {
+		return nil
+	}
+	if repoService.Store == nil {
+		log.Warnf("Crawler disabled: %s", ErrIndexDisabled.Error())
+		return nil
+	}
+
+	return &Crawler{
+		repoService: repoService,
+		sources:     config.CrawlerSources,
+		interval:    config.CrawlerInterval,
+	}
+}
+
+func (c *Crawler) Start(ctx context.Context) {
+	ctx, c.cancel = context.WithCancel(ctx)
+
+	for _, name := range c.sources {
+		github, err := c.repoService.source(name)
+		if err != nil {
+			log.Errorf("Crawler of %s not started: %s", name, err.Error())
+			continue
+		}
+
+		c.done.Add(1)
+		go func(name string, github port.GithubInterface) {
+			defer c.done.Done()
+			for {
+				before, _, _ := c.repoService.Store.Checkpoint(name)
+				_, err := c.Crawl(name, github)
+				if err != nil {
+					log.Errorf("Crawler error on %s: %s", name, err.Error())
+				}
+				after, _, _ := c.repoService.Store.Checkpoint(name)
+
+				// Caught up with the head, the cursor staying put, or failing, the next page is only requested after the interval
+				wait := time.Duration(0)
+				if after == before || err != nil {
+					wait = c.interval
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(wait):
+				}
+			}
+		}(name, github)
+	}
+}
+
+func (c *Crawler) Stop() {
+	if c.cancel == nil {
+		return
+	}
+	c.cancel()
+	c.done.Wait()
+}
+
+// Crawl indexes the page following the cursor of the source and moves the cursor forward, starting from the head
+// of the source on the first run, it returns the number of repositories saved by this crawl. The cursor stops before
+// the first repository whose enrichment failed, the next crawl requests it again while the stored ones aren't enriched
+// twice. A repository failing crawlerMaxAttempts times is given up and left out of the index
+func (c *Crawler) Crawl(name string, github port.GithubInterface) (int, error) {
+	store := c.repoService.Store
+	cursor, found, err := store.Checkpoint(name)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		if cursor, err = github.GetLatestRepoID(); err != nil || cursor == 0 {
+			return 0, err
+		}
+	}
+
+	repos, last, err := github.GetRepositories(cursor)
+	if err != nil {
+		return 0, err
+	}
+
+	stored := c.repoService.stored(name, repos)
+	enrichments := c.repoService.enrichBatch(github, unstored(repos, stored), nil)
+	budget := newSourceBudget(c.repoService.Config.SourceConcurrency, 0)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		failed  = make(map[int]bool)
+		indexed int
+	)
+	wg.Add(len(repos))
+	for _, repository := range repos {
+		go func(repository *dto.LatestCreatedRepo) {
+			defer wg.Done()
+			if _, ok := stored[repository.ID]; ok {
+				return
+			}
+			budget.acquire()
+			defer budget.release()
+
+			enrichment, ok := enrichments[repository.ID]
+			if !ok {
+				var err error
+				if enrichment, err = c.repoService.enrich(github, repository); err != nil {
+					log.Errorf("Crawler couldn't enrich %s: %s", repository.FullName, err.Error())
+					if c.giveUp(name, repository.ID) {
+						log.Warnf("Crawler gave up on %s after %d failed enrichments, left out of the index", repository.FullName, crawlerMaxAttempts)
+						return
+					}
+					mu.Lock()
+					failed[repository.ID] = true
+					mu.Unlock()
+					return
+				}
+			}
+			c.forget(name, repository.ID)
+			returnedRepository := newListRepoOutput(name, repository)
+			applyEnrichment(returnedRepository, enrichment)
+			c.repoService.save(name, repository.ID, returnedRepository)
+			mu.Lock()
+			indexed++
+			mu.Unlock()
+		}(repository)
+	}
+	wg.Wait()
+
+	next := cursor
+	for _, repository := range repos {
+		if failed[repository.ID] {
+			continue
+		}
+		if !blocked(failed, repository.ID) {
+			next = max(next, repository.ID)
+		}
+	}
+	// The forks ending the page are skipped along with it unless a failure keeps the cursor behind
+	if len(failed) == 0 {
+		next = max(next, last)
+	}
+
+	if err = store.SetCheckpoint(name, next); err != nil {
+		return indexed, err
+	}
+	log.Infof("%d repositories of %s indexed, cursor at %d", indexed, name, next)
+	if len(failed) > 0 {
+		return indexed, errors.New(strconv.Itoa(len(failed)) + " repositories of " + name + " not enriched, cursor kept at " + strconv.Itoa(next))
+	}
+	return indexed, nil
+}
+
+// giveUp counts a failed enrichment of the repository and tells whether it was the last attempt
+func (c *Crawler) giveUp(name string, id int) bool {
+	c.attemptsMu.Lock()
+	defer c.attemptsMu.Unlock()
+
+	if c.attempts == nil {
+		c.attempts = make(map[string]map[int]int)
+	}
+	if c.attempts[name] == nil {
+		c.attempts[name] = make(map[int]int)
+	}
+	c.attempts[name][id]++
+	if c.attempts[name][id] < crawlerMaxAttempts {
+		return false
+	}
+	delete(c.attempts[name], id)
+	return true
+}
+
+// forget clears the failed enrichments of a repository once it is enriched
+func (c *Crawler) forget(name string, id int) {
+	c.attemptsMu.Lock()
+	defer c.attemptsMu.Unlock()
+	delete(c.attempts[name], id)
+}
+
+// blocked tells whether a repository with a lower ID failed, the cursor can't move past the failure
+func blocked(failed map[int]bool, id int) bool {
+	for failedID := range failed {
+		if failedID < id {
+			return true
+		}
+	}
+	return false
+}
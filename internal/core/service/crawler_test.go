@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"scalingo/internal/core/domain"
+	"scalingo/internal/core/port"
+	"scalingo/internal/infra/config"
+	"scalingo/internal/infra/search"
+
+	"github.com/stretchr/testify/assert"
+	s "github.com/stretchr/testify/suite"
+)
+
+type CrawlerSuite struct {
+	s.Suite
+	store       *mockStore
+	repoService *RepoService
+	crawler     *Crawler
+}
+
+func (suite *CrawlerSuite) SetupTest() {
+	suite.store = &mockStore{repositories: map[int]*domain.StoredRepository{}}
+	cfg := &config.Config{OutputSize: 4, CrawlerEnabled: true, CrawlerSources: []string{port.DefaultSource}}
+	suite.repoService = ProvideRepoService(cfg, &mockGithub{}, nil, nil, suite.store, nil)
+	suite.crawler = ProvideCrawler(cfg, suite.repoService)
+}
+
+func (suite *CrawlerSuite) TearDownTest() {}
+
+func (suite *CrawlerSuite) TestCrawl_Checkpoint() {
+	crawled, err := suite.crawler.Crawl(port.DefaultSource, &mockGithub{})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 4, crawled)
+	assert.Equal(suite.T(), 4, len(suite.store.repositories))
+	assert.Equal(suite.T(), 4, suite.store.checkpoint)
+	assert.Equal(suite.T(), "bob_smith/repo_four", suite.store.repositories[4].Repository.FullName)
+}
+
+func (suite *CrawlerSuite) TestCrawl_FailedEnrichment() {
+	crawled, err := suite.crawler.Crawl(port.DefaultSource, &mockRateLimitedGithub{})
+
+	assert.ErrorContains(suite.T(), err, "1 repositories of github not enriched, cursor kept at 1")
+	assert.Equal(suite.T(), 3, crawled)
+	assert.Equal(suite.T(), 1, suite.store.checkpoint)
+	assert.NotContains(suite.T(), suite.store.repositories, 2)
+
+	// Once the rate limit is reset, the failed repository is indexed and the cursor moves past the page
+	crawled, err = suite.crawler.Crawl(port.DefaultSource, &mockGithub{})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, crawled) // the 3 others were saved by the first crawl
+	assert.Equal(suite.T(), 4, suite.store.checkpoint)
+	assert.Equal(suite.T(), "GPL-3.0", suite.store.repositories[2].Repository.License)
+}
+
+func (suite *CrawlerSuite) TestCrawl_GivesUp() {
+	for attempt := 1; attempt < crawlerMaxAttempts; attempt++ {
+		_, err := suite.crawler.Crawl(port.DefaultSource, &mockRateLimitedGithub{})
+		assert.Error(suite.T(), err)
+		assert.Equal(suite.T(), 1, suite.store.checkpoint)
+	}
+
+	crawled, err := suite.crawler.Crawl(port.DefaultSource, &mockRateLimitedGithub{})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, crawled)
+	assert.Equal(suite.T(), 4, suite.store.checkpoint)
+	assert.NotContains(suite.T(), suite.store.repositories, 2)
+}
+
+func (suite *CrawlerSuite) TestCrawl_ForkPage() {
+	crawled, err := suite.crawler.Crawl(port.DefaultSource, &mockForkPageSource{})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, crawled)
+	assert.Equal(suite.T(), 10, suite.store.checkpoint)
+}
+
+func (suite *CrawlerSuite) TestListRepositories_FromIndex() {
+	_, err := suite.crawler.Crawl(port.DefaultSource, &mockGithub{})
+	assert.NoError(suite.T(), err)
+	suite.repoService.Github = &mockFailingSource{}
+
+	output, err := suite.repoService.ListRepositories(context.Background(), &domain.ListRepoInput{Language: "java"})
+
+	assert.NoError(suite.T(), err)
+	// The newest first, Javascript matching too
+	assert.Equal(suite.T(), 3, len(output))
+	assert.Equal(suite.T(), "alice_smith/repo_three", output[0].FullName)
+	assert.Equal(suite.T(), "john_doe/repo_one", output[2].FullName)
+
+	output, err = suite.repoService.ListRepositories(context.Background(), &domain.ListRepoInput{NameContains: "four"})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(output))
+
+	_, err = suite.repoService.ListRepositories(context.Background(), &domain.ListRepoInput{Mode: domain.LiveMode})
+
+	assert.Error(suite.T(), err)
+}
+
+func (suite *CrawlerSuite) TestDisabledWithoutStore() {
+	cfg := &config.Config{CrawlerEnabled: true}
+	repoService := ProvideRepoService(cfg, &mockGithub{}, nil, nil, nil, nil)
+
+	assert.Nil(suite.T(), ProvideCrawler(cfg, repoService))
+	_, err := repoService.ListRepositories(context.Background(), &domain.ListRepoInput{Mode: domain.IndexMode})
+	assert.ErrorIs(suite.T(), err, ErrIndexDisabled)
+}
+
+func (suite *CrawlerSuite) TestListRepositories_Text() {
+	suite.repoService.Search = search.NewIndex(10)
+	_, err := suite.crawler.Crawl(port.DefaultSource, &mockGithub{})
+	assert.NoError(suite.T(), err)
+
+	output, err := suite.repoService.ListRepositories(context.Background(), &domain.ListRepoInput{Text: "samples"})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 4, len(output))
+	assert.Contains(suite.T(), output[0].Highlights["description"], "<em>sample</em> repository")
+
+	output, err = suite.repoService.ListRepositories(context.Background(), &domain.ListRepoInput{Text: "api", License: "GPL"})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(output))
+	assert.Equal(suite.T(), "jane_doe/repo_two", output[0].FullName)
+	assert.Equal(suite.T(), "<em>api</em> sample repository", output[0].Highlights["description"])
+	assert.Greater(suite.T(), output[0].Score, 0.0)
+}
+
+func TestCrawlerSuite(t *testing.T) {
+	s.Run(t, new(CrawlerSuite))
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+
+	"scalingo/internal/core/domain"
+	"scalingo/internal/core/dto"
+	"scalingo/internal/core/port"
+)
+
+const (
+	eventTypeSuffix = "Event"
+	// Events buffered for a live subscriber before they are dropped
+	streamBuffer = 256
+)
+
+var ErrEventsDisabled = errors.New("events are not available: GITHUB_EVENTS_POLLING is disabled")
+
+func ProvideEventService(source port.EventSourceInterface) *EventService {
+	return &EventService{
+		Source: source,
+	}
+}
+
+type EventService struct {
+	Source port.EventSourceInterface
+}
+
+// ListEvents returns the buffered events matching the filters, oldest first, limited to the most recent ones
+func (e *EventService) ListEvents(_ context.Context, eventsInput *domain.ListEventsInput) ([]*domain.Event, error) {
+	if e.Source == nil {
+		return nil, ErrEventsDisabled
+	}
+
+	events := make([]*domain.Event, 0)
+	for _, event := range e.Source.Recent() {
+		if matchEvent(eventsInput, event) {
+			events = append(events, toDomainEvent(event))
+		}
+	}
+
+	if eventsInput.Limit > 0 && len(events) > eventsInput.Limit {
+		events = events[len(events)-eventsInput.Limit:]
+	}
+	return events, nil
+}
+
+// StreamEvents forwards the events matching the filters as they are polled, until the context is done
+func (e *EventService) StreamEvents(ctx context.Context, eventsInput *domain.ListEventsInput) (<-chan *domain.Event, error) {
+	if e.Source == nil {
+		return nil, ErrEventsDisabled
+	}
+
+	events, unsubscribe := e.Source.Subscribe(streamBuffer)
+	stream := make(chan *domain.Event)
+
+	go func() {
+		defer close(stream)
+		defer unsubscribe()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				if !matchEvent(eventsInput, event) {
+					continue
+				}
+				select {
+				case stream <- toDomainEvent(event):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return stream, nil
+}
+
+func matchEvent(eventsInput *domain.ListEventsInput, event *dto.Event) bool {
+	if len(eventsInput.Types) > 0 {
+		matched := false
+		for _, eventType := range eventsInput.Types {
+			if strings.EqualFold(eventType+eventTypeSuffix, event.Type) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if eventsInput.Actor != "" && !strings.EqualFold(eventsInput.Actor, event.Actor) {
+		return false
+	}
+
+	if eventsInput.Repo != "" {
+		// The pattern has been validated, e.g. "kubernetes/*" or "*/awesome-*"
+		if matched, _ := path.Match(strings.ToLower(eventsInput.Repo), strings.ToLower(event.RepoName)); !matched {
+			return false
+		}
+	}
+
+	return eventsInput.RefType == "" || strings.EqualFold(eventsInput.RefType, event.RefType)
+}
+
+func toDomainEvent(event *dto.Event) *domain.Event {
+	return &domain.Event{
+		ID:        event.ID,
+		Type:      event.Type,
+		Actor:     event.Actor,
+		Repo:      event.RepoName,
+		Action:    event.Action,
+		Ref:       event.Ref,
+		RefType:   event.RefType,
+		CreatedAt: event.CreatedAt,
+	}
+}
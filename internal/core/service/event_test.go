@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"scalingo/internal/core/domain"
+	"scalingo/internal/core/dto"
+
+	"github.com/stretchr/testify/assert"
+	s "github.com/stretchr/testify/suite"
+)
+
+type EventServiceSuite struct {
+	s.Suite
+	source       *mockEventSource
+	eventService *EventService
+}
+
+type mockEventSource struct {
+	events     []*dto.Event
+	subscriber chan *dto.Event
+}
+
+func (m *mockEventSource) Recent() []*dto.Event { return m.events }
+
+func (m *mockEventSource) Subscribe(buffer int) (events <-chan *dto.Event, unsubscribe func()) {
+	m.subscriber = make(chan *dto.Event, buffer)
+	return m.subscriber, func() {}
+}
+
+func (suite *EventServiceSuite) SetupTest() {
+	suite.source = &mockEventSource{events: []*dto.Event{
+		{ID: "1", Type: "PushEvent", Actor: "john_doe", RepoName: "kubernetes/kubernetes", Ref: "refs/heads/main"},
+		{ID: "2", Type: "CreateEvent", Actor: "jane_doe", RepoName: "jane_doe/new", RefType: "repository"},
+		{ID: "3", Type: "CreateEvent", Actor: "john_doe", RepoName: "kubernetes/website", RefType: "tag", Ref: "v1.0.0"},
+		{ID: "4", Type: "WatchEvent", Actor: "bob_jones", RepoName: "kubernetes/kubernetes", Action: "started"},
+	}}
+	suite.eventService = ProvideEventService(suite.source)
+}
+
+func (suite *EventServiceSuite) TearDownTest() {}
+
+func (suite *EventServiceSuite) TestListEvents_Filters() {
+	events, err := suite.eventService.ListEvents(context.Background(), &domain.ListEventsInput{
+		Types: []string{"Push", "Create"},
+		Repo:  "kubernetes/*",
+	})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, len(events))
+	assert.Equal(suite.T(), "1", events[0].ID)
+	assert.Equal(suite.T(), "3", events[1].ID)
+
+	events, err = suite.eventService.ListEvents(context.Background(), &domain.ListEventsInput{Actor: "JOHN_DOE", RefType: "tag"})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(events))
+	assert.Equal(suite.T(), "v1.0.0", events[0].Ref)
+}
+
+func (suite *EventServiceSuite) TestListEvents_Limit() {
+	events, err := suite.eventService.ListEvents(context.Background(), &domain.ListEventsInput{Limit: 1})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(events))
+	assert.Equal(suite.T(), "4", events[0].ID)
+}
+
+func (suite *EventServiceSuite) TestStreamEvents() {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := suite.eventService.StreamEvents(ctx, &domain.ListEventsInput{Types: []string{"Fork"}})
+	assert.NoError(suite.T(), err)
+
+	suite.source.subscriber <- &dto.Event{ID: "5", Type: "PushEvent"}
+	suite.source.subscriber <- &dto.Event{ID: "6", Type: "ForkEvent"}
+
+	select {
+	case event := <-stream:
+		assert.Equal(suite.T(), "6", event.ID)
+	case <-time.After(time.Second):
+		suite.T().Fatal("no event streamed")
+	}
+
+	cancel()
+	_, open := <-stream
+	assert.False(suite.T(), open)
+}
+
+func (suite *EventServiceSuite) TestListEvents_Disabled() {
+	_, err := ProvideEventService(nil).ListEvents(context.Background(), &domain.ListEventsInput{})
+
+	assert.ErrorIs(suite.T(), err, ErrEventsDisabled)
+}
+
+func TestEventServiceSuite(t *testing.T) {
+	s.Run(t, new(EventServiceSuite))
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"scalingo/internal/core/domain"
+	"scalingo/internal/core/port"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrBudgetExhausted  = errors.New("request budget exhausted")
+	ErrAllSourcesFailed = errors.New("every repository source failed")
+)
+
+// sourceBudget bounds the concurrent enrichments and the upstream requests of a source during a federated search,
+// a nil budget is unlimited
+type sourceBudget struct {
+	slots     chan struct{}
+	remaining atomic.Int64
+	limited   bool
+}
+
+func newSourceBudget(concurrency, requests int) *sourceBudget {
+	budget := &sourceBudget{limited: requests > 0}
+	if concurrency > 0 {
+		budget.slots = make(chan struct{}, concurrency)
+	}
+	budget.remaining.Store(int64(requests))
+	return budget
+}
+
+// spend takes n requests from the budget, false once it is exhausted
+func (b *sourceBudget) spend(n int) bool {
+	if b == nil || !b.limited {
+		return true
+	}
+	return b.remaining.Add(-int64(n)) >= 0
+}
+
+func (b *sourceBudget) acquire() {
+	if b != nil && b.slots != nil {
+		b.slots <- struct{}{}
+	}
+}
+
+func (b *sourceBudget) release() {
+	if b != nil && b.slots != nil {
+		<-b.slots
+	}
+}
+
+// SearchRepositories fans the listing out across every configured source, each one within its own budget,
+// the results are merged and sorted by full name, or by rank for the text searches, a failing source only adds its error to the output
+func (p *RepoService) SearchRepositories(ctx context.Context, repoInput *domain.ListRepoInput) (*domain.FederatedRepoOutput, error) {
+	output, err := p.coalesce(ctx, "search", repoInput, func(ctx context.Context, repoInput *domain.ListRepoInput) (any, error) {
+		return p.searchRepositories(ctx, repoInput)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return output.(*domain.FederatedRepoOutput).Clone(), nil
+}
+
+func (p *RepoService) searchRepositories(ctx context.Context, repoInput *domain.ListRepoInput) (*domain.FederatedRepoOutput, error) {
+	sources := p.Sources
+	if len(sources) == 0 {
+		sources = port.Sources{port.DefaultSource: p.Github}
+	}
+
+	output := &domain.FederatedRepoOutput{Repositories: make([]*domain.ListRepoOutput, 0), Errors: map[string]string{}}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(len(sources))
+	for name, github := range sources {
+		go func(name string, github port.GithubInterface) {
+			defer wg.Done()
+
+			budget := newSourceBudget(p.Config.SourceConcurrency, p.Config.SourceRequestBudget)
+			repos, err := p.scan(ctx, name, github, repoInput, budget)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				log.Warnf("Source %s failed during the federated search: %s", name, err.Error())
+				output.Errors[name] = err.Error()
+			}
+			output.Repositories = append(output.Repositories, repos...)
+		}(name, github)
+	}
+	wg.Wait()
+
+	if len(output.Errors) == len(sources) && len(output.Repositories) == 0 {
+		return nil, ErrAllSourcesFailed
+	}
+
+	sort.SliceStable(output.Repositories, func(i, j int) bool {
+		left, right := output.Repositories[i], output.Repositories[j]
+		if repoInput.Text != "" && left.Score != right.Score {
+			return left.Score > right.Score
+		}
+		if !strings.EqualFold(left.FullName, right.FullName) {
+			return strings.ToLower(left.FullName) < strings.ToLower(right.FullName)
+		}
+		return left.Source < right.Source
+	})
+	// The merged output is bounded like the listing of a single source
+	if len(output.Repositories) > p.Config.OutputSize {
+		output.Repositories = output.Repositories[:p.Config.OutputSize]
+	}
+	return output, nil
+}
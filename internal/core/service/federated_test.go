@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"scalingo/internal/core/domain"
+	"scalingo/internal/core/dto"
+	"scalingo/internal/core/port"
+	"scalingo/internal/infra/config"
+
+	"github.com/stretchr/testify/assert"
+	s "github.com/stretchr/testify/suite"
+)
+
+type FederatedSuite struct {
+	s.Suite
+	repoService *RepoService
+}
+
+type mockFailingSource struct{ mockGithub }
+
+func (m *mockFailingSource) GetLatestRepoID() (int, error) {
+	return 0, errors.New("503 service unavailable")
+}
+
+// mockFailingPageSource lists its first page, then fails
+type mockFailingPageSource struct{ mockGithub }
+
+func (m *mockFailingPageSource) GetRepositories(since int) ([]*dto.LatestCreatedRepo, int, error) {
+	if since > 1 {
+		return nil, since, errors.New("502 bad gateway")
+	}
+	return m.mockGithub.GetRepositories(since)
+}
+
+func (suite *FederatedSuite) SetupTest() {
+	suite.repoService = ProvideRepoService(&config.Config{OutputSize: 4}, &mockGithub{}, port.Sources{
+		port.DefaultSource: &mockGithub{},
+		"gitlab":           &mockBatchGithub{},
+		"codeberg":         &mockFailingSource{},
+	}, nil, nil, nil)
+}
+
+func (suite *FederatedSuite) TearDownTest() {}
+
+func (suite *FederatedSuite) TestSearchRepositories_PartialResults() {
+	output, err := suite.repoService.SearchRepositories(context.Background(), &domain.ListRepoInput{Source: domain.AllSources})
+
+	assert.NoError(suite.T(), err)
+	// 8 repositories listed by the two sources left, the first 4 by full name kept
+	assert.Equal(suite.T(), 4, len(output.Repositories))
+	assert.Equal(suite.T(), map[string]string{"codeberg": "503 service unavailable"}, output.Errors)
+	for i := 1; i < len(output.Repositories); i++ {
+		assert.LessOrEqual(suite.T(), output.Repositories[i-1].FullName, output.Repositories[i].FullName)
+	}
+	assert.Equal(suite.T(), port.DefaultSource, output.Repositories[0].Source)
+	assert.Equal(suite.T(), "gitlab", output.Repositories[1].Source)
+}
+
+func (suite *FederatedSuite) TestSearchRepositories_Filtered() {
+	output, err := suite.repoService.SearchRepositories(context.Background(), &domain.ListRepoInput{
+		Source:       domain.AllSources,
+		NameContains: "two",
+	})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, len(output.Repositories))
+}
+
+func (suite *FederatedSuite) TestSearchRepositories_Budget() {
+	// The latest ID and the first page only, the repositories are left without languages and license
+	suite.repoService.Config.SourceRequestBudget = 2
+	suite.repoService.Sources = port.Sources{port.DefaultSource: &mockGithub{}}
+
+	output, err := suite.repoService.SearchRepositories(context.Background(), &domain.ListRepoInput{Source: domain.AllSources})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 4, len(output.Repositories))
+	for _, repository := range output.Repositories {
+		assert.Empty(suite.T(), repository.Languages)
+	}
+}
+
+func (suite *FederatedSuite) TestSearchRepositories_FailedMidScan() {
+	suite.repoService.Config.OutputSize = 8
+	suite.repoService.Sources = port.Sources{"codeberg": &mockFailingPageSource{}}
+
+	output, err := suite.repoService.SearchRepositories(context.Background(), &domain.ListRepoInput{Source: domain.AllSources})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 4, len(output.Repositories))
+	assert.Equal(suite.T(), map[string]string{"codeberg": "502 bad gateway"}, output.Errors)
+}
+
+func (suite *FederatedSuite) TestSearchRepositories_AllFailed() {
+	suite.repoService.Sources = port.Sources{"codeberg": &mockFailingSource{}}
+
+	output, err := suite.repoService.SearchRepositories(context.Background(), &domain.ListRepoInput{Source: domain.AllSources})
+
+	assert.ErrorIs(suite.T(), err, ErrAllSourcesFailed)
+	assert.Nil(suite.T(), output)
+}
+
+func TestFederatedSuite(t *testing.T) {
+	s.Run(t, new(FederatedSuite))
+}
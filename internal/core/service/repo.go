@@ -2,59 +2,188 @@ package service
 
 import (
 	"context"
+	"errors"
 	"scalingo/internal/core/domain"
 	"scalingo/internal/core/dto"
 	"scalingo/internal/core/port"
 	conf "scalingo/internal/infra/config"
+	"scalingo/internal/shared"
 	"strings"
 	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
-func ProvideRepoService(config *conf.Config, github port.GithubInterface) *RepoService {
+func ProvideRepoService(
+	config *conf.Config,
+	github port.GithubInterface,
+	sources port.Sources,
+	cache port.CacheInterface,
+	store port.RepoStoreInterface,
+	search port.SearchIndexInterface,
+) *RepoService {
+	cachedSources := make(port.Sources, len(sources))
+	for name, source := range sources {
+		cachedSources[name] = withCache(config, source, cache)
+	}
+
 	return &RepoService{
-		Config: config,
-		Github: github,
+		Config:  config,
+		Github:  withCache(config, github, cache),
+		Sources: cachedSources,
+		Store:   store,
+		Search:  search,
+
+		inflight: shared.NewCoalescer("ListRepositories call"),
 	}
 }
 
 type RepoService struct {
-	Config *conf.Config
-	Github port.GithubInterface
-}
+	Config  *conf.Config
+	Github  port.GithubInterface
+	Sources port.Sources
+	Store   port.RepoStoreInterface
+	Search  port.SearchIndexInterface
 
-// Concurrent safe repository ID holder for next batched request to GitHub
-type lowestID struct {
-	sync.RWMutex
-	ID            int
-	LastProcessed int
+	inflight *shared.Coalescer
 }
 
 func (p *RepoService) ListRepositories(ctx context.Context, repoInput *domain.ListRepoInput) ([]*domain.ListRepoOutput, error) {
+	listOutput, err := p.coalesce(ctx, "list", repoInput, func(ctx context.Context, repoInput *domain.ListRepoInput) (any, error) {
+		return p.listRepositories(ctx, repoInput)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return domain.CloneRepositories(listOutput.([]*domain.ListRepoOutput)), nil
+}
+
+func (p *RepoService) listRepositories(ctx context.Context, repoInput *domain.ListRepoInput) ([]*domain.ListRepoOutput, error) {
+	github, err := p.source(repoInput.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	name := repoInput.Source
+	if name == "" {
+		name = port.DefaultSource
+	}
+	return p.scan(ctx, name, github, repoInput, nil)
+}
+
+// scan answers from the index of the crawler unless a live scan of the source is requested or no index is available
+func (p *RepoService) scan(
+	ctx context.Context,
+	name string,
+	github port.GithubInterface,
+	repoInput *domain.ListRepoInput,
+	budget *sourceBudget,
+) ([]*domain.ListRepoOutput, error) {
+	switch {
+	case repoInput.Text != "" && p.Search == nil:
+		return nil, ErrSearchDisabled
+	case repoInput.Text != "":
+		return p.searchText(name, repoInput), nil
+	case repoInput.Mode == domain.IndexMode && p.Store == nil:
+		return nil, ErrIndexDisabled
+	case repoInput.Mode == domain.IndexMode, repoInput.Mode == "" && p.Store != nil && p.Config.CrawlerEnabled:
+		return p.listIndexed(name, repoInput)
+	}
+	return p.list(ctx, name, github, repoInput, budget)
+}
+
+// searchText ranks the indexed repositories of the source matching the text, the other filters still apply
+func (p *RepoService) searchText(name string, repoInput *domain.ListRepoInput) []*domain.ListRepoOutput {
+	hits := p.Search.Search(repoInput.Text, name, p.Config.OutputSize, func(repository *domain.ListRepoOutput) bool {
+		return matchesEnriched(repoInput, repository)
+	})
+
+	listOutput := make([]*domain.ListRepoOutput, 0, len(hits))
+	for _, hit := range hits {
+		returnedRepository := *hit.Repository
+		returnedRepository.Source = name
+		returnedRepository.Score = hit.Score
+		returnedRepository.Highlights = hit.Highlights
+		listOutput = append(listOutput, &returnedRepository)
+	}
+	return listOutput
+}
+
+// listedOf rebuilds the listed fields the filters apply to from an enriched repository
+func listedOf(repository *domain.ListRepoOutput) *dto.LatestCreatedRepo {
+	return &dto.LatestCreatedRepo{
+		Name:        repository.FullName[strings.LastIndex(repository.FullName, "/")+1:],
+		Description: repository.Description,
+	}
+}
+
+// matchesEnriched applies the filters of the input to an enriched repository
+func matchesEnriched(repoInput *domain.ListRepoInput, repository *domain.ListRepoOutput) bool {
+	return filter(repoInput, listedOf(repository), repository.License, repository.Languages, repository.RepoSize())
+}
+
+// listIndexed filters the stored repositories of the source, the newest first, until the output is fulfilled
+func (p *RepoService) listIndexed(name string, repoInput *domain.ListRepoInput) ([]*domain.ListRepoOutput, error) {
+	listOutput := make([]*domain.ListRepoOutput, 0)
+	err := p.Store.List(name, func(stored *domain.StoredRepository) bool {
+		returnedRepository := *stored.Repository
+		returnedRepository.Source = name
+
+		if matchesEnriched(repoInput, &returnedRepository) {
+			listOutput = append(listOutput, &returnedRepository)
+		}
+		return len(listOutput) < p.Config.OutputSize
+	})
+	if err != nil {
+		return nil, err
+	}
+	return listOutput, nil
+}
+
+// list scans a single source, within the budget when one is given, the repositories listed before the budget
+// is exhausted are returned along with ErrBudgetExhausted
+func (p *RepoService) list(
+	ctx context.Context,
+	name string,
+	github port.GithubInterface,
+	repoInput *domain.ListRepoInput,
+	budget *sourceBudget,
+) ([]*domain.ListRepoOutput, error) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	id, err := p.Github.GetLatestRepoID()
+	if !budget.spend(1) {
+		return nil, ErrBudgetExhausted
+	}
+	id, err := github.GetLatestRepoID()
 	if err != nil || id == 0 {
 		return nil, err
 	}
 
-	lowestIDForNextBatch := &lowestID{ID: id, LastProcessed: id}
 	listOutput := make([]*domain.ListRepoOutput, 0)
 	requested := 0
 
 	// While the output size if not fulfilled
 	for requested < p.Config.OutputSize {
 		reposChannel := make(chan *domain.ListRepoOutput, p.Config.ProcessingBatchSize)
+		if !budget.spend(1) {
+			return listOutput, ErrBudgetExhausted
+		}
 		var currentList []*dto.LatestCreatedRepo
-		currentList, err = p.Github.GetRepositories(id)
+		var next int
+		currentList, next, err = github.GetRepositories(id)
 		if err != nil {
-			return nil, err
+			// The repositories listed before the failure are returned along with it
+			return listOutput, err
+		}
+		// The source has nothing newer to list, a page of forks only still moves the cursor
+		if next <= id {
+			break
 		}
 		log.Infof(
 			"Current batch ID %d for %d number to retrieve and %d retrieved in last request, %d left",
-			lowestIDForNextBatch.ID,
+			id,
 			p.Config.OutputSize,
 			len(currentList),
 			p.Config.OutputSize-len(currentList),
@@ -62,6 +191,12 @@ func (p *RepoService) ListRepositories(ctx context.Context, repoInput *domain.Li
 
 		requested += len(currentList)
 
+		// The repositories already in the store are neither enriched again nor requested to the source
+		stored := p.stored(name, currentList)
+
+		// Sources able to enrich a whole page at once spare the languages and license requests of every repository
+		enrichments := p.enrichBatch(github, unstored(currentList, stored), budget)
+
 		var wg sync.WaitGroup
 		wg.Add(len(currentList))
 
@@ -72,13 +207,10 @@ func (p *RepoService) ListRepositories(ctx context.Context, repoInput *domain.Li
 				repository *dto.LatestCreatedRepo,
 			) { // loop var anonymous parameter not necessary anymore since 1.22, see https://go.dev/blog/loopvar-preview
 				defer wg.Done()
+				budget.acquire()
+				defer budget.release()
 
-				returnedRepository := &domain.ListRepoOutput{
-					FullName:    repository.FullName,
-					Owner:       repository.Owner.Login,
-					Repository:  repository.HTMLURL,
-					Description: repository.Description,
-				}
+				returnedRepository := newListRepoOutput(name, repository)
 
 				// Cancel context for leftover routines if the listOutput is full,
 				// actually not really necessary since the above processing (returnedRepository assignation) is really fast
@@ -91,24 +223,30 @@ func (p *RepoService) ListRepositories(ctx context.Context, repoInput *domain.Li
 				default:
 				}
 
-				returnedRepository.Languages, err = p.Github.GetRepositoryLanguages(repository.LanguagesURL)
-				if err != nil {
-					log.Errorf("couldn't retrieve languages: %#v", err)
+				enrichment, ok := enrichments[repository.ID]
+				storedRepository, isStored := stored[repository.ID]
+				switch {
+				case isStored:
+					*returnedRepository = *storedRepository
+					returnedRepository.Source = name
+				case ok:
+				case budget.spend(2): // languages and license requests
+					var err error
+					enrichment, err = p.enrich(github, repository)
+					// A failed enrichment is returned but not saved, the next listings request it again
+					ok = err == nil
+				default:
+					log.Warnf("Request budget of %s exhausted, %s left without languages and license", name, repository.FullName)
+					enrichment = &dto.RepositoryEnrichment{}
 				}
-
-				returnedRepository.License, err = p.Github.GetRepositorySPDX(repository.URL)
-				if err != nil {
-					log.Errorf("couldn't retrieve spdx: %#v", err)
+				if !isStored {
+					applyEnrichment(returnedRepository, enrichment)
 				}
-
-				// We want the lowest ID of the current batch to use it as the next 'since' query parameter to GitHub
-				if lowestIDForNextBatch.ID < repository.ID {
-					lowestIDForNextBatch.Lock()
-					lowestIDForNextBatch.ID = repository.ID
-					lowestIDForNextBatch.Unlock()
+				if ok {
+					p.save(name, repository.ID, returnedRepository)
 				}
 
-				if p.filter(
+				if filter(
 					repoInput,
 					repository,
 					returnedRepository.License,
@@ -134,14 +272,156 @@ func (p *RepoService) ListRepositories(ctx context.Context, repoInput *domain.Li
 				break
 			}
 		}
-		id = lowestIDForNextBatch.ID
+		// The highest listed ID, forks included, is the 'since' query parameter of the next batch
+		id = next
 	}
 
 	return listOutput, nil
 }
 
+func newListRepoOutput(name string, repository *dto.LatestCreatedRepo) *domain.ListRepoOutput {
+	return &domain.ListRepoOutput{
+		Source:      name,
+		FullName:    repository.FullName,
+		Owner:       repository.Owner.Login,
+		Repository:  repository.HTMLURL,
+		Description: repository.Description,
+	}
+}
+
+// source selects the repository source of the request, github.com when none is requested
+func (p *RepoService) source(name string) (port.GithubInterface, error) {
+	if name == "" || (name == port.DefaultSource && p.Sources[name] == nil) {
+		return p.Github, nil
+	}
+
+	github, ok := p.Sources[name]
+	if !ok {
+		return nil, errors.New("unknown source: " + name)
+	}
+	return github, nil
+}
+
+// stored reads the repositories of the page through the store, the missing and expired ones are left out
+func (p *RepoService) stored(name string, repos []*dto.LatestCreatedRepo) map[int]*domain.ListRepoOutput {
+	if p.Store == nil {
+		return nil
+	}
+
+	stored := make(map[int]*domain.ListRepoOutput)
+	for _, repository := range repos {
+		storedRepository, ok, err := p.Store.Get(name, repository.ID)
+		if err != nil {
+			log.Errorf("couldn't read stored repository: %#v", err)
+			continue
+		}
+		if ok {
+			stored[repository.ID] = storedRepository.Repository
+		}
+	}
+	return stored
+}
+
+func unstored(repos []*dto.LatestCreatedRepo, stored map[int]*domain.ListRepoOutput) []*dto.LatestCreatedRepo {
+	if len(stored) == 0 {
+		return repos
+	}
+
+	result := make([]*dto.LatestCreatedRepo, 0, len(repos)-len(stored))
+	for _, repository := range repos {
+		if _, ok := stored[repository.ID]; !ok {
+			result = append(result, repository)
+		}
+	}
+	return result
+}
+
+// save persists the enriched repository and indexes it for the text search
+func (p *RepoService) save(name string, id int, repository *domain.ListRepoOutput) {
+	if p.Search != nil {
+		p.Search.Index(name, id, repository)
+	}
+	if p.Store == nil {
+		return
+	}
+
+	err := p.Store.Save(&domain.StoredRepository{Source: name, ID: id, FetchedAt: time.Now(), Repository: repository})
+	if err != nil {
+		log.Errorf("couldn't store repository: %#v", err)
+	}
+}
+
+func (p *RepoService) enrichBatch(
+	github port.GithubInterface,
+	repos []*dto.LatestCreatedRepo,
+	budget *sourceBudget,
+) map[int]*dto.RepositoryEnrichment {
+	batch, ok := github.(port.GithubBatchInterface)
+	if !ok || len(repos) == 0 || !budget.spend(1) {
+		return nil
+	}
+
+	enrichments, err := batch.EnrichRepositories(repos)
+	if err != nil {
+		log.Errorf("couldn't enrich repositories batch, falling back to one request per repository: %#v", err)
+		return nil
+	}
+	return enrichments
+}
+
+// enrich requests the languages and license of the repository, along with the error of a failed request,
+// e.g. a rate limit or a server error, the partial enrichment is then not worth saving
+func (p *RepoService) enrich(github port.GithubInterface, repository *dto.LatestCreatedRepo) (*dto.RepositoryEnrichment, error) {
+	failures := make([]string, 0)
+	languages, err := github.GetRepositoryLanguages(repository.LanguagesURL)
+	if err != nil {
+		log.Errorf("couldn't retrieve languages: %#v", err)
+		failures = append(failures, err.Error())
+	}
+
+	details, err := github.GetRepository(repository.URL)
+	if err != nil {
+		log.Errorf("couldn't retrieve spdx: %#v", err)
+		failures = append(failures, err.Error())
+	}
+	if details == nil {
+		details = &dto.RepositoryDetails{}
+	}
+
+	enrichment := &dto.RepositoryEnrichment{
+		FullName:      details.FullName,
+		HTMLURL:       details.HTMLURL,
+		License:       details.License,
+		Languages:     languages,
+		LanguagesUnit: details.LanguagesUnit,
+	}
+	if len(failures) > 0 {
+		return enrichment, errors.New("error while enriching " + repository.FullName + ": " + strings.Join(failures, shared.Separator))
+	}
+	return enrichment, nil
+}
+
+// applyEnrichment fills the languages and license, and reports the current name of the renamed or transferred repositories
+func applyEnrichment(returnedRepository *domain.ListRepoOutput, enrichment *dto.RepositoryEnrichment) {
+	returnedRepository.Languages = enrichment.Languages
+	returnedRepository.LanguagesUnit = enrichment.LanguagesUnit
+	returnedRepository.License = enrichment.License
+
+	if enrichment.FullName == "" || strings.EqualFold(enrichment.FullName, returnedRepository.FullName) {
+		return
+	}
+	returnedRepository.PreviousFullName = returnedRepository.FullName
+	returnedRepository.FullName = enrichment.FullName
+	if owner, _, ok := strings.Cut(enrichment.FullName, "/"); ok {
+		returnedRepository.Owner = owner
+	}
+	if enrichment.HTMLURL != "" {
+		returnedRepository.Repository = enrichment.HTMLURL
+	}
+}
+
 //nolint:gocyclo
-func (p *RepoService) filter(
+func filter(
 	repoInput *domain.ListRepoInput,
 	repository *dto.LatestCreatedRepo,
 	spdx string,
@@ -168,7 +448,8 @@ func (p *RepoService) filter(
 		}
 	}
 
-	if repoInput.MinSize > 0 {
+	// The size filters don't apply to the repositories of unknown size, their languages not being reported in bytes
+	if repoInput.MinSize > 0 && repoSize >= 0 {
 		switch repoSize > repoInput.MinSize {
 		case true:
 			validateFilters["min_size"] = true
@@ -177,7 +458,7 @@ func (p *RepoService) filter(
 		}
 	}
 
-	if repoInput.MaxSize > 0 {
+	if repoInput.MaxSize > 0 && repoSize >= 0 {
 		switch repoSize < repoInput.MaxSize {
 		case true:
 			validateFilters["max_size"] = true
@@ -2,10 +2,16 @@ package service
 
 import (
 	"context"
+	"errors"
 	"scalingo/internal/core/domain"
 	"scalingo/internal/core/dto"
+	"scalingo/internal/core/port"
 	"scalingo/internal/infra/config"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	s "github.com/stretchr/testify/suite"
@@ -19,7 +25,7 @@ type RepoServiceSuite struct {
 type mockGithub struct{}
 
 func (m *mockGithub) GetLatestRepoID() (int, error) { return 1, nil }
-func (m *mockGithub) GetRepositories(_ int) ([]*dto.LatestCreatedRepo, error) {
+func (m *mockGithub) GetRepositories(_ int) ([]*dto.LatestCreatedRepo, int, error) {
 	return []*dto.LatestCreatedRepo{
 		{
 			ID:       1,
@@ -69,7 +75,7 @@ func (m *mockGithub) GetRepositories(_ int) ([]*dto.LatestCreatedRepo, error) {
 			URL:          "https://api.github.com/repos/bob_jones/repo_four",
 			Description:  "fourth sample repository",
 		},
-	}, nil
+	}, 4, nil
 }
 
 func (m *mockGithub) GetRepositoryLanguages(fullURL string) (map[string]int, error) {
@@ -97,7 +103,7 @@ func (m *mockGithub) GetRepositoryLanguages(fullURL string) (map[string]int, err
 	return languages, nil
 }
 
-func (m *mockGithub) GetRepositorySPDX(fullURL string) (string, error) {
+func (m *mockGithub) GetRepository(fullURL string) (*dto.RepositoryDetails, error) {
 	var spdx string
 	switch fullURL {
 	case "https://api.github.com/repos/john_doe/repo_one":
@@ -107,13 +113,35 @@ func (m *mockGithub) GetRepositorySPDX(fullURL string) (string, error) {
 	case "https://api.github.com/repos/alice_smith/repo_three":
 		spdx = "AGPL-3.0"
 	case "https://api.github.com/repos/bob_jones/repo_four":
-		spdx = "BSD-2"
+		// Transferred since the listing, GitHub redirects to the new owner
+		return &dto.RepositoryDetails{
+			FullName: "bob_smith/repo_four",
+			HTMLURL:  "https://github.com/bob_smith/repo_four",
+			License:  "BSD-2",
+		}, nil
 	}
-	return spdx, nil
+	return &dto.RepositoryDetails{License: spdx}, nil
+}
+
+// mockBatchGithub enriches the whole page at once, except the last repository which falls back to the single requests
+type mockBatchGithub struct {
+	mockGithub
+}
+
+func (m *mockBatchGithub) EnrichRepositories(repos []*dto.LatestCreatedRepo) (map[int]*dto.RepositoryEnrichment, error) {
+	enrichments := map[int]*dto.RepositoryEnrichment{}
+	for _, repo := range repos[:len(repos)-1] {
+		enrichments[repo.ID] = &dto.RepositoryEnrichment{
+			FullName:  repo.FullName,
+			License:   "Apache-2.0",
+			Languages: map[string]int{"Rust": 20},
+		}
+	}
+	return enrichments, nil
 }
 
 func (suite *RepoServiceSuite) SetupTest() {
-	suite.repoService = ProvideRepoService(&config.Config{OutputSize: 4}, &mockGithub{})
+	suite.repoService = ProvideRepoService(&config.Config{OutputSize: 4}, &mockGithub{}, nil, nil, nil, nil)
 }
 
 func (suite *RepoServiceSuite) TearDownTest() {}
@@ -156,6 +184,28 @@ func (suite *RepoServiceSuite) TestListRepositories_SizeRange() {
 	}
 }
 
+// mockBasisPointsSource reports the languages of mockGithub as shares, as GitLab does
+type mockBasisPointsSource struct{ mockGithub }
+
+func (m *mockBasisPointsSource) GetRepository(fullURL string) (*dto.RepositoryDetails, error) {
+	details, err := m.mockGithub.GetRepository(fullURL)
+	details.LanguagesUnit = dto.BasisPointsUnit
+	return details, err
+}
+
+func (suite *RepoServiceSuite) TestListRepositories_SizeRangeUnknownSize() {
+	suite.repoService = ProvideRepoService(&config.Config{OutputSize: 4}, &mockBasisPointsSource{}, nil, nil, nil, nil)
+
+	output, err := suite.repoService.ListRepositories(context.Background(), &domain.ListRepoInput{MinSize: 10, MaxSize: 500})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 4, len(output))
+	for _, repo := range output {
+		assert.Equal(suite.T(), dto.BasisPointsUnit, repo.LanguagesUnit)
+		assert.Equal(suite.T(), int64(-1), repo.RepoSize())
+	}
+}
+
 func (suite *RepoServiceSuite) TestListRepositories_Language() {
 	repoInput := &domain.ListRepoInput{Language: "Go"}
 	output, err := suite.repoService.ListRepositories(context.Background(), repoInput)
@@ -180,6 +230,262 @@ func (suite *RepoServiceSuite) TestListRepositories_License() {
 	}
 }
 
+func (suite *RepoServiceSuite) TestListRepositories_Transferred() {
+	output, err := suite.repoService.ListRepositories(context.Background(), &domain.ListRepoInput{License: "BSD"})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(output))
+	assert.Equal(suite.T(), "bob_smith/repo_four", output[0].FullName)
+	assert.Equal(suite.T(), "bob_jones/repo_four", output[0].PreviousFullName)
+	assert.Equal(suite.T(), "bob_smith", output[0].Owner)
+	assert.Equal(suite.T(), "https://github.com/bob_smith/repo_four", output[0].Repository)
+}
+
+func (suite *RepoServiceSuite) TestListRepositories_BatchEnrichment() {
+	suite.repoService = ProvideRepoService(&config.Config{OutputSize: 4}, &mockBatchGithub{}, nil, nil, nil, nil)
+
+	output, err := suite.repoService.ListRepositories(context.Background(), &domain.ListRepoInput{License: "Apache"})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 3, len(output))
+	for _, repo := range output {
+		assert.Equal(suite.T(), map[string]int{"Rust": 20}, repo.Languages)
+	}
+}
+
+func (suite *RepoServiceSuite) TestListRepositories_UnknownSource() {
+	output, err := suite.repoService.ListRepositories(context.Background(), &domain.ListRepoInput{Source: "unknown"})
+
+	assert.Error(suite.T(), err)
+	assert.Nil(suite.T(), output)
+}
+
+// mockEmptySource has nothing newer than the latest ID to list
+type mockEmptySource struct{ mockGithub }
+
+func (m *mockEmptySource) GetRepositories(since int) ([]*dto.LatestCreatedRepo, int, error) {
+	return []*dto.LatestCreatedRepo{}, since, nil
+}
+
+// mockForkPageSource lists a first page of forks only before the repositories of mockGithub
+type mockForkPageSource struct{ mockGithub }
+
+func (m *mockForkPageSource) GetRepositories(since int) ([]*dto.LatestCreatedRepo, int, error) {
+	if since < 10 {
+		return []*dto.LatestCreatedRepo{}, 10, nil
+	}
+	repos, _, err := m.mockGithub.GetRepositories(since)
+	return repos, since + len(repos), err
+}
+
+func (suite *RepoServiceSuite) TestListRepositories_ForkPage() {
+	suite.repoService = ProvideRepoService(&config.Config{OutputSize: 4}, &mockForkPageSource{}, nil, nil, nil, nil)
+
+	output, err := suite.repoService.ListRepositories(context.Background(), &domain.ListRepoInput{})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 4, len(output))
+}
+
+func (suite *RepoServiceSuite) TestListRepositories_ExhaustedSource() {
+	suite.repoService = ProvideRepoService(&config.Config{OutputSize: 4}, &mockGithub{}, port.Sources{"gitlab": &mockEmptySource{}}, nil, nil, nil)
+
+	output, err := suite.repoService.ListRepositories(context.Background(), &domain.ListRepoInput{Source: "gitlab"})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, len(output))
+}
+
+// mockSlowSource blocks the latest ID requests until released and counts them
+type mockSlowSource struct {
+	mockGithub
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (m *mockSlowSource) GetLatestRepoID() (int, error) {
+	m.calls.Add(1)
+	<-m.release
+	return 1, nil
+}
+
+func (suite *RepoServiceSuite) TestListRepositories_Coalesced() {
+	source := &mockSlowSource{release: make(chan struct{})}
+	suite.repoService = ProvideRepoService(&config.Config{OutputSize: 4}, source, port.Sources{port.DefaultSource: source}, nil, nil, nil)
+
+	var wg sync.WaitGroup
+	inputs := []*domain.ListRepoInput{{Language: "Go"}, {Language: " go"}, {Language: "GO", Source: port.DefaultSource}}
+	wg.Add(len(inputs))
+	for _, input := range inputs {
+		go func(input *domain.ListRepoInput) {
+			defer wg.Done()
+			_, err := suite.repoService.ListRepositories(context.Background(), input)
+			assert.NoError(suite.T(), err)
+		}(input)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(source.release)
+	wg.Wait()
+
+	assert.Equal(suite.T(), int32(1), source.calls.Load())
+}
+
+func (suite *RepoServiceSuite) TestListRepositories_CoalescedCancelled() {
+	source := &mockSlowSource{release: make(chan struct{})}
+	suite.repoService = ProvideRepoService(&config.Config{OutputSize: 4}, source, port.Sources{port.DefaultSource: source}, nil, nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leaderDone := make(chan error)
+	go func() {
+		_, err := suite.repoService.ListRepositories(ctx, &domain.ListRepoInput{})
+		leaderDone <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	outputs := make([][]*domain.ListRepoOutput, 2)
+	wg.Add(len(outputs))
+	for i := range outputs {
+		go func(i int) {
+			defer wg.Done()
+			var err error
+			outputs[i], err = suite.repoService.ListRepositories(context.Background(), &domain.ListRepoInput{})
+			assert.NoError(suite.T(), err)
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	assert.ErrorIs(suite.T(), <-leaderDone, context.Canceled)
+	close(source.release)
+	wg.Wait()
+
+	assert.Equal(suite.T(), int32(1), source.calls.Load())
+	assert.NotEmpty(suite.T(), outputs[0])
+	assert.Equal(suite.T(), outputs[0], outputs[1])
+	assert.NotSame(suite.T(), outputs[0][0], outputs[1][0])
+	outputs[0][0].Languages["Go"] = 0
+	assert.NotEqual(suite.T(), outputs[0][0].Languages, outputs[1][0].Languages)
+}
+
+// mockStore keeps the saved repositories in memory
+type mockStore struct {
+	sync.Mutex
+	repositories map[int]*domain.StoredRepository
+	checkpoint   int
+}
+
+func (m *mockStore) Get(_ string, id int) (*domain.StoredRepository, bool, error) {
+	m.Lock()
+	defer m.Unlock()
+	repository, ok := m.repositories[id]
+	return repository, ok, nil
+}
+
+func (m *mockStore) Save(repository *domain.StoredRepository) error {
+	m.Lock()
+	defer m.Unlock()
+	m.repositories[repository.ID] = repository
+	return nil
+}
+
+// Batch saves the repositories once fn succeeds, as the transaction of a real store
+func (m *mockStore) Batch(fn func(save func(repository *domain.StoredRepository) error) error) error {
+	batch := make([]*domain.StoredRepository, 0)
+	if err := fn(func(repository *domain.StoredRepository) error {
+		batch = append(batch, repository)
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, repository := range batch {
+		if err := m.Save(repository); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mockStore) List(_ string, fn func(repository *domain.StoredRepository) bool) error {
+	m.Lock()
+	defer m.Unlock()
+	ids := make([]int, 0, len(m.repositories))
+	for id := range m.repositories {
+		ids = append(ids, id)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+	for _, id := range ids {
+		if !fn(m.repositories[id]) {
+			break
+		}
+	}
+	return nil
+}
+
+func (m *mockStore) Sources() ([]string, error) {
+	m.Lock()
+	defer m.Unlock()
+	sources := make([]string, 0)
+	for _, repository := range m.repositories {
+		if len(sources) == 0 || sources[len(sources)-1] != repository.Source {
+			sources = append(sources, repository.Source)
+		}
+	}
+	return sources, nil
+}
+
+func (m *mockStore) Checkpoint(_ string) (int, bool, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.checkpoint, m.checkpoint != 0, nil
+}
+
+func (m *mockStore) SetCheckpoint(_ string, id int) error {
+	m.Lock()
+	defer m.Unlock()
+	m.checkpoint = id
+	return nil
+}
+
+func (m *mockStore) Prune(_ time.Time) (int, error) { return 0, nil }
+func (m *mockStore) Compact() error                 { return nil }
+
+func (suite *RepoServiceSuite) TestListRepositories_ReadThroughStore() {
+	store := &mockStore{repositories: map[int]*domain.StoredRepository{
+		1: {Source: "github", ID: 1, Repository: &domain.ListRepoOutput{FullName: "john_doe/repo_one", License: "stored"}},
+	}}
+	suite.repoService = ProvideRepoService(&config.Config{OutputSize: 4}, &mockGithub{}, nil, nil, store, nil)
+
+	output, err := suite.repoService.ListRepositories(context.Background(), &domain.ListRepoInput{License: "stored"})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(output))
+	assert.Equal(suite.T(), "github", output[0].Source)
+	assert.Equal(suite.T(), 4, len(store.repositories))
+	assert.Equal(suite.T(), "GPL-3.0", store.repositories[2].Repository.License)
+}
+
+// mockRateLimitedGithub fails the languages request of the second repository, as when the rate limit is hit
+type mockRateLimitedGithub struct{ mockGithub }
+
+func (m *mockRateLimitedGithub) GetRepositoryLanguages(fullURL string) (map[string]int, error) {
+	if fullURL == "https://api.github.com/repos/jane_doe/repo_two/languages" {
+		return map[string]int{}, errors.New("error while getting repository languages: 403 - API rate limit exceeded")
+	}
+	return m.mockGithub.GetRepositoryLanguages(fullURL)
+}
+
+func (suite *RepoServiceSuite) TestListRepositories_FailedEnrichmentNotStored() {
+	store := &mockStore{repositories: map[int]*domain.StoredRepository{}}
+	suite.repoService = ProvideRepoService(&config.Config{OutputSize: 4}, &mockRateLimitedGithub{}, nil, nil, store, nil)
+
+	output, err := suite.repoService.ListRepositories(context.Background(), &domain.ListRepoInput{})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 4, len(output))
+	assert.Equal(suite.T(), 3, len(store.repositories))
+	assert.NotContains(suite.T(), store.repositories, 2)
+}
+
 func TestRepoServiceSuite(t *testing.T) {
 	s.Run(t, new(RepoServiceSuite))
 }
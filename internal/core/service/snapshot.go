@@ -0,0 +1,162 @@
+package service
+
+import (
+	"io"
+	"sort"
+	"time"
+
+	"scalingo/internal/core/domain"
+	"scalingo/internal/core/port"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SnapshotService exports the stored repositories to a snapshot archive and loads them back, to share a crawl
+// or to analyze it offline
+type SnapshotService struct {
+	store   port.RepoStoreInterface
+	search  port.SearchIndexInterface
+	archive port.SnapshotArchiveInterface
+}
+
+func ProvideSnapshotService(
+	store port.RepoStoreInterface,
+	search port.SearchIndexInterface,
+	archive port.SnapshotArchiveInterface,
+) *SnapshotService {
+	return &SnapshotService{store: store, search: search, archive: archive}
+}
+
+// Export writes the stored repositories of the sources accepted by the filters, the newest first, along with the cursors
+// of the crawler
+func (s *SnapshotService) Export(w io.Writer, exportInput *domain.SnapshotExportInput) (*domain.SnapshotManifest, error) {
+	if s.store == nil {
+		return nil, ErrIndexDisabled
+	}
+
+	sources := exportInput.Sources
+	if len(sources) == 0 {
+		stored, err := s.store.Sources()
+		if err != nil {
+			return nil, err
+		}
+		sources = stored
+	}
+	filters := exportInput.Filters
+	if filters != nil {
+		filters = filters.Normalize("")
+	}
+
+	header := &domain.SnapshotHeader{
+		Version:   domain.SnapshotVersion,
+		CreatedAt: time.Now().UTC(),
+		Sources:   make(map[string]*domain.SnapshotSource, len(sources)),
+		Filters:   filters,
+	}
+	for _, name := range sources {
+		checkpoint, _, err := s.store.Checkpoint(name)
+		if err != nil {
+			return nil, err
+		}
+		header.Sources[name] = &domain.SnapshotSource{Checkpoint: checkpoint}
+	}
+
+	writer, err := s.archive.NewWriter(w, header)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range sources {
+		var writeErr error
+		err = s.store.List(name, func(stored *domain.StoredRepository) bool {
+			if filters != nil && !matchesEnriched(filters, stored.Repository) {
+				return true
+			}
+			writeErr = writer.Write(stored)
+			return writeErr == nil
+		})
+		if err == nil {
+			err = writeErr
+		}
+		if err != nil {
+			_, _ = writer.Close()
+			return nil, err
+		}
+	}
+	return writer.Close()
+}
+
+// Import saves the repositories of the archive to the store in a single transaction as they are decoded, none of them
+// when the archive doesn't match its manifest, then indexes them. They are stamped with the time of the import, the
+// retention of the store counting from it. The cursors of the crawler are restored for the sources it never crawled
+func (s *SnapshotService) Import(r io.Reader) (*domain.SnapshotImportOutput, error) {
+	if s.store == nil {
+		return nil, ErrIndexDisabled
+	}
+
+	var (
+		snapshot *domain.Snapshot
+		imported []snapshotKey
+	)
+	importedAt := time.Now()
+	err := s.store.Batch(func(save func(repository *domain.StoredRepository) error) error {
+		var err error
+		snapshot, err = s.archive.Stream(r, func(stored *domain.StoredRepository) error {
+			stored.FetchedAt = importedAt
+			imported = append(imported, snapshotKey{source: stored.Source, id: stored.ID})
+			return save(stored)
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.search != nil {
+		if err = s.index(imported); err != nil {
+			return nil, err
+		}
+	}
+
+	for name, source := range snapshot.Header.Sources {
+		if source.Checkpoint == 0 {
+			continue
+		}
+		_, found, err := s.store.Checkpoint(name)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			if err = s.store.SetCheckpoint(name, source.Checkpoint); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	log.Infof("%d repositories imported from a snapshot created at %s", len(imported), snapshot.Header.CreatedAt)
+	return &domain.SnapshotImportOutput{
+		Repositories: len(imported),
+		Sources:      snapshot.Manifest.Sources,
+	}, nil
+}
+
+// snapshotKey identifies an imported repository, only the keys are kept while the archive is streamed
+type snapshotKey struct {
+	source string
+	id     int
+}
+
+// index reads the imported repositories back from the store into the search index, the oldest first, the newest
+// repositories are then the last ones evicted from the index
+func (s *SnapshotService) index(imported []snapshotKey) error {
+	sort.SliceStable(imported, func(i, j int) bool { return imported[i].id < imported[j].id })
+	for _, key := range imported {
+		stored, found, err := s.store.Get(key.source, key.id)
+		if err != nil {
+			return err
+		}
+		if found {
+			s.search.Index(stored.Source, stored.ID, stored.Repository)
+		}
+	}
+	return nil
+}
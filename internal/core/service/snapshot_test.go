@@ -0,0 +1,99 @@
+package service
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"scalingo/internal/core/domain"
+	"scalingo/internal/infra/snapshot"
+
+	"github.com/stretchr/testify/assert"
+	s "github.com/stretchr/testify/suite"
+)
+
+type SnapshotSuite struct {
+	s.Suite
+	store           *mockStore
+	snapshotService *SnapshotService
+}
+
+func (suite *SnapshotSuite) SetupTest() {
+	suite.store = &mockStore{repositories: map[int]*domain.StoredRepository{
+		1: {Source: "github", ID: 1, FetchedAt: time.Now(), Repository: &domain.ListRepoOutput{
+			FullName: "john_doe/repo_one", License: "mit", Languages: map[string]int{"Go": 100},
+		}},
+		2: {Source: "github", ID: 2, FetchedAt: time.Now(), Repository: &domain.ListRepoOutput{
+			FullName: "john_doe/repo_two", License: "GPL-3.0", Languages: map[string]int{"Python": 100},
+		}},
+	}, checkpoint: 2}
+	suite.snapshotService = ProvideSnapshotService(suite.store, nil, snapshot.ProvideArchive())
+}
+
+func (suite *SnapshotSuite) TestExportImport() {
+	var archive bytes.Buffer
+	manifest, err := suite.snapshotService.Export(&archive, &domain.SnapshotExportInput{
+		Filters: &domain.ListRepoInput{Language: "GO"},
+	})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, manifest.Repositories)
+
+	target := &mockStore{repositories: map[int]*domain.StoredRepository{}}
+	output, err := ProvideSnapshotService(target, nil, snapshot.ProvideArchive()).Import(&archive)
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, output.Repositories)
+	assert.Equal(suite.T(), "john_doe/repo_one", target.repositories[1].Repository.FullName)
+	assert.Equal(suite.T(), 2, target.checkpoint)
+}
+
+func (suite *SnapshotSuite) TestImport_KeepsCheckpoint() {
+	var archive bytes.Buffer
+	_, err := suite.snapshotService.Export(&archive, &domain.SnapshotExportInput{Sources: []string{"github"}})
+	assert.NoError(suite.T(), err)
+
+	target := &mockStore{repositories: map[int]*domain.StoredRepository{}, checkpoint: 10}
+	output, err := ProvideSnapshotService(target, nil, snapshot.ProvideArchive()).Import(&archive)
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, output.Repositories)
+	assert.Equal(suite.T(), 10, target.checkpoint)
+}
+
+// TestImport_Restamped imports the repositories of an old snapshot as fetched at the time of the import
+func (suite *SnapshotSuite) TestImport_Restamped() {
+	suite.store.repositories[1].FetchedAt = time.Now().AddDate(0, -1, 0)
+	var archive bytes.Buffer
+	_, err := suite.snapshotService.Export(&archive, &domain.SnapshotExportInput{})
+	assert.NoError(suite.T(), err)
+
+	target := &mockStore{repositories: map[int]*domain.StoredRepository{}}
+	_, err = ProvideSnapshotService(target, nil, snapshot.ProvideArchive()).Import(&archive)
+
+	assert.NoError(suite.T(), err)
+	assert.WithinDuration(suite.T(), time.Now(), target.repositories[1].FetchedAt, time.Minute)
+}
+
+func (suite *SnapshotSuite) TestImport_Truncated() {
+	var archive bytes.Buffer
+	_, err := suite.snapshotService.Export(&archive, &domain.SnapshotExportInput{})
+	assert.NoError(suite.T(), err)
+
+	target := &mockStore{repositories: map[int]*domain.StoredRepository{}}
+	_, err = ProvideSnapshotService(target, nil, snapshot.ProvideArchive()).Import(bytes.NewReader(archive.Bytes()[:archive.Len()-20]))
+
+	assert.Error(suite.T(), err)
+	assert.Empty(suite.T(), target.repositories)
+}
+
+func (suite *SnapshotSuite) TestStoreDisabled() {
+	suite.snapshotService = ProvideSnapshotService(nil, nil, snapshot.ProvideArchive())
+
+	_, err := suite.snapshotService.Export(&bytes.Buffer{}, &domain.SnapshotExportInput{})
+
+	assert.ErrorIs(suite.T(), err, ErrIndexDisabled)
+}
+
+func TestSnapshotSuite(t *testing.T) {
+	s.Run(t, new(SnapshotSuite))
+}
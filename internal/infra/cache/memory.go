@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"scalingo/internal/core/dto"
+	"scalingo/internal/core/port"
+	conf "scalingo/internal/infra/config"
+)
+
+// ProvideCache builds the in-memory cache, nil when the caching is disabled
+func ProvideCache(config *conf.Config) port.CacheInterface {
+	if !config.CacheEnabled {
+		return nil
+	}
+	return NewMemory(config.CacheSize)
+}
+
+// Memory is a size bounded LRU cache, the least recently used entry is evicted once the capacity is reached
+// and the expired entries are dropped when they are read
+type Memory struct {
+	sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List // most recently used first
+
+	hits      int64
+	misses    int64
+	evictions int64
+}
+
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+func NewMemory(capacity int) *Memory {
+	return &Memory{
+		capacity: max(capacity, 1),
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (m *Memory) Get(key string) ([]byte, bool) {
+	m.Lock()
+	defer m.Unlock()
+
+	element, ok := m.entries[key]
+	if !ok {
+		m.misses++
+		return nil, false
+	}
+	entry := element.Value.(*memoryEntry)
+	if time.Now().After(entry.expires) {
+		m.remove(element)
+		m.misses++
+		return nil, false
+	}
+
+	m.order.MoveToFront(element)
+	m.hits++
+	return entry.value, true
+}
+
+func (m *Memory) Set(key string, value []byte, ttl time.Duration) {
+	m.Lock()
+	defer m.Unlock()
+
+	entry := &memoryEntry{key: key, value: value, expires: time.Now().Add(ttl)}
+	if element, ok := m.entries[key]; ok {
+		element.Value = entry
+		m.order.MoveToFront(element)
+		return
+	}
+
+	m.entries[key] = m.order.PushFront(entry)
+	for m.order.Len() > m.capacity {
+		m.remove(m.order.Back())
+		m.evictions++
+	}
+}
+
+func (m *Memory) Delete(key string) {
+	m.Lock()
+	defer m.Unlock()
+
+	if element, ok := m.entries[key]; ok {
+		m.remove(element)
+	}
+}
+
+func (m *Memory) Purge() {
+	m.Lock()
+	defer m.Unlock()
+
+	m.entries = make(map[string]*list.Element)
+	m.order.Init()
+}
+
+func (m *Memory) Stats() *dto.CacheStats {
+	m.Lock()
+	defer m.Unlock()
+
+	return &dto.CacheStats{
+		Entries:   m.order.Len(),
+		Capacity:  m.capacity,
+		Hits:      m.hits,
+		Misses:    m.misses,
+		Evictions: m.evictions,
+	}
+}
+
+func (m *Memory) remove(element *list.Element) {
+	m.order.Remove(element)
+	delete(m.entries, element.Value.(*memoryEntry).key)
+}
@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	s "github.com/stretchr/testify/suite"
+)
+
+type MemorySuite struct {
+	s.Suite
+	memory *Memory
+}
+
+func (suite *MemorySuite) SetupTest() {
+	suite.memory = NewMemory(2)
+}
+
+func (suite *MemorySuite) TearDownTest() {}
+
+func (suite *MemorySuite) TestLRUEviction() {
+	suite.memory.Set("one", []byte("1"), time.Minute)
+	suite.memory.Set("two", []byte("2"), time.Minute)
+	_, _ = suite.memory.Get("one")
+	suite.memory.Set("three", []byte("3"), time.Minute)
+
+	_, ok := suite.memory.Get("two")
+	assert.False(suite.T(), ok)
+	value, ok := suite.memory.Get("one")
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), []byte("1"), value)
+
+	stats := suite.memory.Stats()
+	assert.Equal(suite.T(), 2, stats.Entries)
+	assert.Equal(suite.T(), int64(2), stats.Hits)
+	assert.Equal(suite.T(), int64(1), stats.Misses)
+	assert.Equal(suite.T(), int64(1), stats.Evictions)
+}
+
+func (suite *MemorySuite) TestExpiration() {
+	suite.memory.Set("one", []byte("1"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	_, ok := suite.memory.Get("one")
+	assert.False(suite.T(), ok)
+	assert.Equal(suite.T(), 0, suite.memory.Stats().Entries)
+}
+
+func (suite *MemorySuite) TestDeleteAndPurge() {
+	suite.memory.Set("one", []byte("1"), time.Minute)
+	suite.memory.Set("two", []byte("2"), time.Minute)
+
+	suite.memory.Delete("one")
+	assert.Equal(suite.T(), 1, suite.memory.Stats().Entries)
+
+	suite.memory.Purge()
+	_, ok := suite.memory.Get("two")
+	assert.False(suite.T(), ok)
+}
+
+func TestMemorySuite(t *testing.T) {
+	s.Run(t, new(MemorySuite))
+}
@@ -1,6 +1,10 @@
 package config
 
 import (
+	"os"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
 )
@@ -8,17 +12,85 @@ import (
 type Config struct {
 	GinMode string
 
-	GitHubToken            string
-	GitHubCredentials      bool
+	GitHubToken       string
+	GitHubTokens      []string
+	GitHubCredentials bool
+
+	GitHubAppID             string
+	GitHubAppInstallationID string
+	GitHubAppPrivateKeyFile string
+
+	GitHubMode       string
+	GitHubOfflineDir string
+
 	GitHubURL              string
 	GitHubVersion          string
+	GitHubDetectVersion    bool
+	GitHubMaxRedirects     int
 	LatestCreatedRepoRetry int
+	GitHubHeadStrategy     string
+
+	GitHubEventsPolling      bool
+	GitHubEventsPollInterval time.Duration
+	GitHubEventsMaxPages     int
+	GitHubEventsBufferSize   int
+
+	GitHubEnterpriseURLs   map[string]string
+	GitHubEnterpriseTokens map[string]string
+
+	GitHubTransport string
+	GitHubProxy     string
+	GitHubCABundle  string
+	GitHubHTTP2     bool
+
+	GitHubMaxResponseSize int
+
+	GitHubCassette     string
+	GitHubCassetteMode string
+
+	GitHubConditionalRequests  bool
+	GitHubConditionalCacheSize int
+
+	GitHubAPI        string
+	GitHubGraphQLURL string
+
+	GitLabURL   string
+	GitLabToken string
+
+	GiteaURLs   map[string]string
+	GiteaTokens map[string]string
 
 	OutputSize          int
 	ProcessingBatchSize int
 
+	SourceConcurrency   int
+	SourceRequestBudget int
+
+	CacheEnabled      bool
+	CacheSize         int
+	CacheLanguagesTTL time.Duration
+	CacheLicenseTTL   time.Duration
+	CacheNegativeTTL  time.Duration
+
+	ResponseCacheTTL   time.Duration
+	ResponseCacheStale time.Duration
+	ResponseCacheSize  int
+
+	StorePath               string
+	StoreRetention          time.Duration
+	StoreCompactionInterval time.Duration
+
+	CrawlerEnabled  bool
+	CrawlerSources  []string
+	CrawlerInterval time.Duration
+
+	SearchEnabled      bool
+	SearchMaxDocuments int
+
 	HTTPPort    string
 	HTTPAddress string
+
+	AdminToken string
 }
 
 func ProvideConfig() *Config {
@@ -31,17 +103,85 @@ func ProvideConfig() *Config {
 	return &Config{
 		GinMode: viper.GetString("GIN_MODE"),
 
-		GitHubToken:            viper.GetString("GITHUB_TOKEN"),
-		GitHubCredentials:      viper.GetBool("GITHUB_CREDENTIALS"),
+		GitHubToken:       viper.GetString("GITHUB_TOKEN"),
+		GitHubTokens:      readTokens(viper.GetString("GITHUB_TOKENS"), viper.GetString("GITHUB_TOKENS_FILE")),
+		GitHubCredentials: viper.GetBool("GITHUB_CREDENTIALS"),
+
+		GitHubAppID:             viper.GetString("GITHUB_APP_ID"),
+		GitHubAppInstallationID: viper.GetString("GITHUB_APP_INSTALLATION_ID"),
+		GitHubAppPrivateKeyFile: viper.GetString("GITHUB_APP_PRIVATE_KEY_FILE"),
+
+		GitHubMode:       viper.GetString("GITHUB_MODE"),
+		GitHubOfflineDir: viper.GetString("GITHUB_OFFLINE_DIR"),
+
 		GitHubURL:              viper.GetString("GITHUB_URL"),
 		GitHubVersion:          viper.GetString("GITHUB_VERSION"),
+		GitHubDetectVersion:    viper.GetBool("GITHUB_DETECT_VERSION"),
+		GitHubMaxRedirects:     viper.GetInt("GITHUB_MAX_REDIRECTS"),
 		LatestCreatedRepoRetry: viper.GetInt("LATEST_CREATED_REPO_RETRY"),
+		GitHubHeadStrategy:     viper.GetString("GITHUB_HEAD_STRATEGY"),
+
+		GitHubEventsPolling:      viper.GetBool("GITHUB_EVENTS_POLLING"),
+		GitHubEventsPollInterval: viper.GetDuration("GITHUB_EVENTS_POLL_INTERVAL"),
+		GitHubEventsMaxPages:     viper.GetInt("GITHUB_EVENTS_MAX_PAGES"),
+		GitHubEventsBufferSize:   viper.GetInt("GITHUB_EVENTS_BUFFER_SIZE"),
+
+		GitHubEnterpriseURLs:   readNamedValues(viper.GetString("GITHUB_ENTERPRISE_URLS")),
+		GitHubEnterpriseTokens: readNamedValues(viper.GetString("GITHUB_ENTERPRISE_TOKENS")),
+
+		GitHubTransport: viper.GetString("GITHUB_TRANSPORT"),
+		GitHubProxy:     viper.GetString("GITHUB_PROXY"),
+		GitHubCABundle:  viper.GetString("GITHUB_CA_BUNDLE"),
+		GitHubHTTP2:     viper.GetBool("GITHUB_HTTP2"),
+
+		GitHubMaxResponseSize: viper.GetInt("GITHUB_MAX_RESPONSE_SIZE"),
+
+		GitHubCassette:     viper.GetString("GITHUB_CASSETTE"),
+		GitHubCassetteMode: viper.GetString("GITHUB_CASSETTE_MODE"),
+
+		GitHubConditionalRequests:  viper.GetBool("GITHUB_CONDITIONAL_REQUESTS"),
+		GitHubConditionalCacheSize: viper.GetInt("GITHUB_CONDITIONAL_CACHE_SIZE"),
+
+		GitHubAPI:        viper.GetString("GITHUB_API"),
+		GitHubGraphQLURL: viper.GetString("GITHUB_GRAPHQL_URL"),
+
+		GitLabURL:   viper.GetString("GITLAB_URL"),
+		GitLabToken: viper.GetString("GITLAB_TOKEN"),
+
+		GiteaURLs:   readNamedValues(viper.GetString("GITEA_URLS")),
+		GiteaTokens: readNamedValues(viper.GetString("GITEA_TOKENS")),
 
 		OutputSize:          viper.GetInt("OUTPUT_SIZE"),
 		ProcessingBatchSize: viper.GetInt("PROCESSING_BATCH_SIZE"),
 
+		SourceConcurrency:   viper.GetInt("SOURCE_CONCURRENCY"),
+		SourceRequestBudget: viper.GetInt("SOURCE_REQUEST_BUDGET"),
+
+		CacheEnabled:      viper.GetBool("CACHE_ENABLED"),
+		CacheSize:         viper.GetInt("CACHE_SIZE"),
+		CacheLanguagesTTL: viper.GetDuration("CACHE_LANGUAGES_TTL"),
+		CacheLicenseTTL:   viper.GetDuration("CACHE_LICENSE_TTL"),
+		CacheNegativeTTL:  viper.GetDuration("CACHE_NEGATIVE_TTL"),
+
+		ResponseCacheTTL:   viper.GetDuration("RESPONSE_CACHE_TTL"),
+		ResponseCacheStale: viper.GetDuration("RESPONSE_CACHE_STALE"),
+		ResponseCacheSize:  viper.GetInt("RESPONSE_CACHE_SIZE"),
+
+		StorePath:               viper.GetString("STORE_PATH"),
+		StoreRetention:          viper.GetDuration("STORE_RETENTION"),
+		StoreCompactionInterval: viper.GetDuration("STORE_COMPACTION_INTERVAL"),
+
+		CrawlerEnabled:  viper.GetBool("CRAWLER_ENABLED"),
+		CrawlerSources:  readTokens(viper.GetString("CRAWLER_SOURCES"), ""),
+		CrawlerInterval: viper.GetDuration("CRAWLER_INTERVAL"),
+
+		SearchEnabled:      viper.GetBool("SEARCH_ENABLED"),
+		SearchMaxDocuments: viper.GetInt("SEARCH_MAX_DOCUMENTS"),
+
 		HTTPPort:    viper.GetString("HTTP_PORT"),
 		HTTPAddress: viper.GetString("HTTP_ADDRESS"),
+
+		AdminToken: viper.GetString("ADMIN_TOKEN"),
 	}
 }
 
@@ -49,14 +189,112 @@ func (c *Config) GetServerAddress() string {
 	return c.HTTPAddress + ":" + c.HTTPPort
 }
 
+// readTokens merges the comma separated tokens with the ones of the file, one per line, '#' starting a comment
+func readTokens(list, file string) []string {
+	tokens := strings.Split(list, ",")
+	if file != "" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			panic("Error reading tokens file: " + err.Error())
+		}
+		tokens = append(tokens, strings.Split(string(content), "\n")...)
+	}
+
+	result := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		token = strings.TrimSpace(token)
+		if token != "" && !strings.HasPrefix(token, "#") {
+			result = append(result, token)
+		}
+	}
+	return result
+}
+
+// readNamedValues parses comma separated name=value pairs, e.g. "corp=https://ghe.corp/api/v3/,lab=https://ghe.lab/api/v3/"
+func readNamedValues(list string) map[string]string {
+	values := make(map[string]string)
+	for _, pair := range strings.Split(list, ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		values[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return values
+}
+
 func initDefault() {
 	viper.SetDefault("GIN_MODE", gin.DebugMode)
 
 	viper.SetDefault("GITHUB_TOKEN", "")
+	viper.SetDefault("GITHUB_TOKENS", "")
+	viper.SetDefault("GITHUB_TOKENS_FILE", "")
 	viper.SetDefault("GITHUB_CREDENTIALS", false)
+	viper.SetDefault("GITHUB_APP_ID", "")
+	viper.SetDefault("GITHUB_APP_INSTALLATION_ID", "")
+	viper.SetDefault("GITHUB_APP_PRIVATE_KEY_FILE", "")
+	viper.SetDefault("GITHUB_MODE", "online")
+	viper.SetDefault("GITHUB_OFFLINE_DIR", "")
 	viper.SetDefault("GITHUB_URL", "")
 	viper.SetDefault("GITHUB_VERSION", "")
+	viper.SetDefault("GITHUB_DETECT_VERSION", true)
+	viper.SetDefault("GITHUB_MAX_REDIRECTS", 3) //nolint: gomnd
+	viper.SetDefault("GITHUB_HEAD_STRATEGY", "events")
+	viper.SetDefault("GITHUB_EVENTS_POLLING", false)
+	viper.SetDefault("GITHUB_EVENTS_POLL_INTERVAL", time.Minute)
+	viper.SetDefault("GITHUB_EVENTS_MAX_PAGES", 3)      //nolint: gomnd
+	viper.SetDefault("GITHUB_EVENTS_BUFFER_SIZE", 1000) //nolint: gomnd
+	viper.SetDefault("GITHUB_ENTERPRISE_URLS", "")
+	viper.SetDefault("GITHUB_ENTERPRISE_TOKENS", "")
+
+	viper.SetDefault("GITHUB_TRANSPORT", "fasthttp")
+	viper.SetDefault("GITHUB_PROXY", "")
+	viper.SetDefault("GITHUB_CA_BUNDLE", "")
+	viper.SetDefault("GITHUB_HTTP2", true)
+
+	viper.SetDefault("GITHUB_MAX_RESPONSE_SIZE", 10<<20) //nolint: gomnd
+
+	viper.SetDefault("GITHUB_CASSETTE", "")
+	viper.SetDefault("GITHUB_CASSETTE_MODE", "replay")
+
+	viper.SetDefault("GITHUB_CONDITIONAL_REQUESTS", true)
+	viper.SetDefault("GITHUB_CONDITIONAL_CACHE_SIZE", 10000) //nolint: gomnd
+
+	viper.SetDefault("GITHUB_API", "rest")
+	viper.SetDefault("GITHUB_GRAPHQL_URL", "")
+
+	viper.SetDefault("GITLAB_URL", "")
+	viper.SetDefault("GITLAB_TOKEN", "")
+
+	viper.SetDefault("GITEA_URLS", "")
+	viper.SetDefault("GITEA_TOKENS", "")
+
+	viper.SetDefault("SOURCE_CONCURRENCY", 10)     //nolint: gomnd
+	viper.SetDefault("SOURCE_REQUEST_BUDGET", 500) //nolint: gomnd
+
+	viper.SetDefault("CACHE_ENABLED", true)
+	viper.SetDefault("CACHE_SIZE", 50000) //nolint: gomnd
+	viper.SetDefault("CACHE_LANGUAGES_TTL", 24*time.Hour)
+	viper.SetDefault("CACHE_LICENSE_TTL", 24*time.Hour)
+	viper.SetDefault("CACHE_NEGATIVE_TTL", 10*time.Minute) //nolint: gomnd
+
+	viper.SetDefault("RESPONSE_CACHE_TTL", time.Minute)
+	viper.SetDefault("RESPONSE_CACHE_STALE", 5*time.Minute) //nolint: gomnd
+	viper.SetDefault("RESPONSE_CACHE_SIZE", 1000)           //nolint: gomnd
+
+	viper.SetDefault("STORE_PATH", "")
+	viper.SetDefault("STORE_RETENTION", 7*24*time.Hour)
+	viper.SetDefault("STORE_COMPACTION_INTERVAL", 24*time.Hour)
+
+	viper.SetDefault("CRAWLER_ENABLED", false)
+	viper.SetDefault("CRAWLER_SOURCES", "github")
+	viper.SetDefault("CRAWLER_INTERVAL", time.Minute)
+
+	viper.SetDefault("SEARCH_ENABLED", true)
+	viper.SetDefault("SEARCH_MAX_DOCUMENTS", 100000) //nolint: gomnd
 
 	viper.SetDefault("HTTP_PORT", 5000) //nolint: gomnd
 	viper.SetDefault("HTTP_ADDRESS", "")
+
+	viper.SetDefault("ADMIN_TOKEN", "")
 }
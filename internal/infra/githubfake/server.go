@@ -0,0 +1,343 @@
+// Package githubfake is an in-process imitation of the GitHub REST API for the integration tests: the events,
+// the repository listing with its since pagination, the repositories and their languages, along with the forks,
+// the null descriptions, the redirections of the renamed repositories and the rate limit headers
+package githubfake
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	// PageSize is the number of repositories of a /repositories page, as on github.com
+	PageSize = 100
+
+	defaultRateLimit = 5000
+)
+
+type Repository struct {
+	ID          int
+	FullName    string
+	Description *string // nil is answered as null
+	Fork        bool
+	License     string // SPDX ID, no license when empty
+	Languages   map[string]int
+
+	// RenamedTo is the current full name of a renamed repository, still listed under FullName whose URLs answer 301
+	RenamedTo string
+}
+
+type Event struct {
+	ID      string
+	Type    string
+	Actor   string
+	RepoID  int
+	Repo    string
+	RefType string
+}
+
+// Server is a fake GitHub answering from the repositories and events it is given, safe for concurrent use
+type Server struct {
+	*httptest.Server
+
+	mu           sync.Mutex
+	repositories []*Repository  // by ascending ID
+	events       []*Event       // newest first
+	failures     map[string]int // status code answered by path
+	requests     []string
+	limit        int
+	remaining    int
+	reset        time.Time
+}
+
+// New starts a fake GitHub closed at the end of the test
+func New(t testing.TB) *Server {
+	s := &Server{
+		failures:  make(map[string]int),
+		limit:     defaultRateLimit,
+		remaining: defaultRateLimit,
+		reset:     time.Now().Add(time.Hour),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// APIURL is the API root to configure as GITHUB_URL
+func (s *Server) APIURL() string {
+	return s.URL + "/"
+}
+
+// AddRepository lists the repository under its ID, forks included as GitHub does
+func (s *Server) AddRepository(repository *Repository) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := sort.Search(len(s.repositories), func(i int) bool { return s.repositories[i].ID >= repository.ID })
+	s.repositories = append(s.repositories, nil)
+	copy(s.repositories[index+1:], s.repositories[index:])
+	s.repositories[index] = repository
+}
+
+// AddEvent publishes the event, the newest first
+func (s *Server) AddEvent(event *Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if event.ID == "" {
+		event.ID = strconv.Itoa(len(s.events) + 1)
+	}
+	s.events = append([]*Event{event}, s.events...)
+}
+
+// Rename moves the repository to a new full name after it was listed, its previous URLs answer 301 to /repositories/{id}
+func (s *Server) Rename(from, to string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, repository := range s.repositories {
+		if strings.EqualFold(repository.FullName, from) {
+			repository.RenamedTo = to
+			return
+		}
+	}
+}
+
+// Fail answers the status code to the requests of the path, e.g. "/repos/john_doe/one/languages"
+func (s *Server) Fail(path string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[path] = status
+}
+
+// SetRateLimit sets the remaining quota, the requests are answered 403 once it is exhausted
+func (s *Server) SetRateLimit(limit, remaining int, reset time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limit, s.remaining, s.reset = limit, remaining, reset
+}
+
+// Requests returns the received requests, as their path and query
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.requests...)
+}
+
+// Count returns the number of received requests of the path, query excluded
+func (s *Server) Count(path string) int {
+	count := 0
+	for _, request := range s.Requests() {
+		if strings.SplitN(request, "?", 2)[0] == path { //nolint: gomnd
+			count++
+		}
+	}
+	return count
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, r.URL.RequestURI())
+	if s.remaining <= 0 {
+		s.rateLimitHeaders(w)
+		writeJSON(w, http.StatusForbidden, map[string]string{"message": "API rate limit exceeded"})
+		return
+	}
+	s.remaining--
+	s.rateLimitHeaders(w)
+
+	if status, ok := s.failures[r.URL.Path]; ok {
+		writeJSON(w, status, map[string]string{"message": http.StatusText(status)})
+		return
+	}
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.URL.Path == "/meta":
+		writeJSON(w, http.StatusOK, map[string]bool{"verifiable_password_authentication": true})
+	case r.URL.Path == "/events":
+		s.serveEvents(w, r)
+	case r.URL.Path == "/repositories":
+		s.serveRepositories(w, r)
+	case len(segments) >= 2 && segments[0] == "repositories": // /repositories/{id}[/languages], the redirection targets
+		id, _ := strconv.Atoi(segments[1])
+		s.serveRepository(w, s.byID(id), segments[2:])
+	case len(segments) >= 3 && segments[0] == "repos": // /repos/{owner}/{repo}[/languages]
+		fullName := segments[1] + "/" + segments[2]
+		if repository := s.byFullName(fullName); repository != nil && repository.RenamedTo != "" {
+			location := s.URL + "/repositories/" + strconv.Itoa(repository.ID)
+			if len(segments) > 3 { //nolint: gomnd
+				location += "/" + strings.Join(segments[3:], "/")
+			}
+			w.Header().Set("Location", location)
+			writeJSON(w, http.StatusMovedPermanently, map[string]string{"message": "Moved Permanently", "url": location})
+			return
+		}
+		s.serveRepository(w, s.byCurrentName(fullName), segments[3:])
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+	}
+}
+
+func (s *Server) rateLimitHeaders(w http.ResponseWriter) {
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(s.limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(max(s.remaining, 0)))
+	w.Header().Set("X-RateLimit-Used", strconv.Itoa(s.limit-max(s.remaining, 0)))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(s.reset.Unix(), 10))
+	w.Header().Set("X-RateLimit-Resource", "core")
+}
+
+// serveEvents pages through the events, per_page at a time, with the Link header of the next page
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request) {
+	perPage, err := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if err != nil || perPage <= 0 {
+		perPage = 30 //nolint: gomnd
+	}
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	from := min((page-1)*perPage, len(s.events))
+	to := min(from+perPage, len(s.events))
+	if to < len(s.events) {
+		w.Header().Set("Link", "<"+s.URL+"/events?per_page="+strconv.Itoa(perPage)+"&page="+strconv.Itoa(page+1)+`>; rel="next"`)
+	}
+	w.Header().Set("X-Poll-Interval", "60")
+
+	events := make([]map[string]any, 0, to-from)
+	for _, event := range s.events[from:to] {
+		payload := map[string]any{}
+		if event.RefType != "" {
+			payload["ref_type"] = event.RefType
+			payload["ref"] = nil
+		}
+		events = append(events, map[string]any{
+			"id":         event.ID,
+			"type":       event.Type,
+			"actor":      map[string]any{"login": event.Actor},
+			"repo":       map[string]any{"id": event.RepoID, "name": event.Repo, "url": s.URL + "/repos/" + event.Repo},
+			"payload":    payload,
+			"public":     true,
+			"created_at": time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+// serveRepositories answers the page of the repositories above since, forks included as GitHub does
+func (s *Server) serveRepositories(w http.ResponseWriter, r *http.Request) {
+	since, _ := strconv.Atoi(r.URL.Query().Get("since"))
+	from := sort.Search(len(s.repositories), func(i int) bool { return s.repositories[i].ID > since })
+	to := min(from+PageSize, len(s.repositories))
+
+	page := make([]map[string]any, 0, to-from)
+	for _, repository := range s.repositories[from:to] {
+		page = append(page, s.listed(repository))
+	}
+	if to > from {
+		w.Header().Set("Link", "<"+s.URL+"/repositories?since="+strconv.Itoa(s.repositories[to-1].ID)+`>; rel="next"`)
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) serveRepository(w http.ResponseWriter, repository *Repository, rest []string) {
+	switch {
+	case repository == nil, len(rest) > 1, len(rest) == 1 && rest[0] != "languages":
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+	case len(rest) == 1:
+		languages := repository.Languages
+		if languages == nil {
+			languages = map[string]int{}
+		}
+		writeJSON(w, http.StatusOK, languages)
+	default:
+		detailed := s.listed(repository)
+		if repository.RenamedTo != "" {
+			detailed = s.listed(&Repository{ID: repository.ID, FullName: repository.RenamedTo, Description: repository.Description, Fork: repository.Fork})
+		}
+		detailed["license"] = nil
+		if repository.License != "" {
+			detailed["license"] = map[string]string{"key": strings.ToLower(repository.License), "spdx_id": repository.License}
+		}
+		writeJSON(w, http.StatusOK, detailed)
+	}
+}
+
+func (s *Server) listed(repository *Repository) map[string]any {
+	owner, name, _ := strings.Cut(repository.FullName, "/")
+	url := s.URL + "/repos/" + repository.FullName
+	return map[string]any{
+		"id":            repository.ID,
+		"node_id":       "R_" + strconv.Itoa(repository.ID),
+		"name":          name,
+		"full_name":     repository.FullName,
+		"owner":         map[string]any{"login": owner},
+		"private":       false,
+		"html_url":      "https://github.com/" + repository.FullName,
+		"description":   repository.Description,
+		"fork":          repository.Fork,
+		"url":           url,
+		"languages_url": url + "/languages",
+	}
+}
+
+func (s *Server) byID(id int) *Repository {
+	for _, repository := range s.repositories {
+		if repository.ID == id {
+			return repository
+		}
+	}
+	return nil
+}
+
+func (s *Server) byFullName(fullName string) *Repository {
+	for _, repository := range s.repositories {
+		if strings.EqualFold(repository.FullName, fullName) {
+			return repository
+		}
+	}
+	return nil
+}
+
+func (s *Server) byCurrentName(fullName string) *Repository {
+	for _, repository := range s.repositories {
+		current := repository.FullName
+		if repository.RenamedTo != "" {
+			current = repository.RenamedTo
+		}
+		if strings.EqualFold(current, fullName) {
+			return repository
+		}
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	body, err := jsoniter.Marshal(value)
+	if err != nil {
+		status, body = http.StatusInternalServerError, []byte(`{"message":"`+err.Error()+`"}`)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// CreateEvent is the event GitHub publishes when the repository is created, the latest one gives the head of the listing
+func CreateEvent(repository *Repository) *Event {
+	owner, _, _ := strings.Cut(repository.FullName, "/")
+	return &Event{Type: "CreateEvent", Actor: owner, RepoID: repository.ID, Repo: repository.FullName, RefType: "repository"}
+}
+
+// Description returns a pointer to the description, the repositories without one have a nil Description
+func Description(description string) *string {
+	return &description
+}
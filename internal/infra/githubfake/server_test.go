@@ -0,0 +1,95 @@
+package githubfake
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	s "github.com/stretchr/testify/suite"
+)
+
+type ServerSuite struct {
+	s.Suite
+	server *Server
+	client *http.Client
+}
+
+func (suite *ServerSuite) SetupTest() {
+	suite.server = New(suite.T())
+	for id := 1; id <= 150; id++ {
+		suite.server.AddRepository(&Repository{ID: id, FullName: "john_doe/repo-" + strconv.Itoa(id), Fork: id == 2})
+	}
+	suite.server.AddRepository(&Repository{ID: 151, FullName: "john_doe/one", Description: Description("One"), Languages: map[string]int{"Go": 10}})
+	suite.client = &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+}
+
+func (suite *ServerSuite) get(path string, value any) *http.Response {
+	resp, err := suite.client.Get(suite.server.URL + path)
+	assert.NoError(suite.T(), err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(suite.T(), err)
+	if value != nil {
+		assert.NoError(suite.T(), jsoniter.Unmarshal(body, value))
+	}
+	return resp
+}
+
+func (suite *ServerSuite) TestRepositories_Pages() {
+	var page []map[string]any
+	resp := suite.get("/repositories?since=0", &page)
+
+	assert.Equal(suite.T(), PageSize, len(page))
+	assert.Equal(suite.T(), true, page[1]["fork"])
+	assert.Nil(suite.T(), page[0]["description"])
+	assert.Equal(suite.T(), `<`+suite.server.URL+`/repositories?since=100>; rel="next"`, resp.Header.Get("Link"))
+
+	suite.get("/repositories?since=100", &page)
+	assert.Equal(suite.T(), 51, len(page))
+	assert.Equal(suite.T(), "One", page[50]["description"])
+
+	resp = suite.get("/repositories?since=151", &page)
+	assert.Empty(suite.T(), page)
+	assert.Empty(suite.T(), resp.Header.Get("Link"))
+}
+
+func (suite *ServerSuite) TestRepository_Renamed() {
+	suite.server.Rename("john_doe/one", "jane_doe/one")
+
+	resp := suite.get("/repos/john_doe/one/languages", nil)
+	assert.Equal(suite.T(), http.StatusMovedPermanently, resp.StatusCode)
+	assert.Equal(suite.T(), suite.server.URL+"/repositories/151/languages", resp.Header.Get("Location"))
+
+	var languages map[string]int
+	suite.get("/repositories/151/languages", &languages)
+	assert.Equal(suite.T(), map[string]int{"Go": 10}, languages)
+
+	var repository map[string]any
+	resp = suite.get("/repos/jane_doe/one", &repository)
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.T(), "jane_doe/one", repository["full_name"])
+	assert.Nil(suite.T(), repository["license"])
+
+	assert.Equal(suite.T(), http.StatusNotFound, suite.get("/repos/john_doe/missing", nil).StatusCode)
+}
+
+func (suite *ServerSuite) TestRateLimit() {
+	suite.server.SetRateLimit(60, 1, time.Unix(1700000000, 0))
+
+	resp := suite.get("/events", nil)
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.T(), "0", resp.Header.Get("X-RateLimit-Remaining"))
+	assert.Equal(suite.T(), "1700000000", resp.Header.Get("X-RateLimit-Reset"))
+
+	resp = suite.get("/events", nil)
+	assert.Equal(suite.T(), http.StatusForbidden, resp.StatusCode)
+	assert.Equal(suite.T(), 2, suite.server.Count("/events"))
+}
+
+func TestServerSuite(t *testing.T) {
+	s.Run(t, new(ServerSuite))
+}
@@ -0,0 +1,180 @@
+package repositories
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"scalingo/internal/shared"
+
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	InstallationTokenEndpoint = "app/installations/{installation_id}/access_tokens"
+	GithubJSONMediaType       = "application/vnd.github+json"
+
+	// GitHub refuses JWTs valid for more than 10 minutes, the issue date is backdated against clock drift
+	appJWTLifetime  = 9 * time.Minute
+	appJWTClockSkew = time.Minute
+	// Installation tokens are renewed before they expire so that no request goes out with a stale token
+	installationTokenRefreshMargin = 5 * time.Minute
+)
+
+// appCredentials authenticates as a GitHub App installation, the installation token obtained with a
+// short-lived RS256 JWT is cached until shortly before it expires and renewed transparently
+type appCredentials struct {
+	sync.Mutex
+	appID     string
+	tokenPath string
+	endpoint  func(path string) string
+	version   string
+	key       *rsa.PrivateKey
+	transport Transport
+	now       func() time.Time
+
+	installationToken string
+	expiresAt         time.Time
+}
+
+type installationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// newAppCredentials resolves the installation token endpoint with the endpoint of the adapter, once the server is detected
+func newAppCredentials(
+	appID, installationID, keyFile string,
+	endpoint func(path string) string,
+	version string,
+	transport Transport,
+) (*appCredentials, error) {
+	pemKey, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, errors.New("error while reading GitHub App private key: " + err.Error())
+	}
+	key, err := parseRSAPrivateKey(pemKey)
+	if err != nil {
+		return nil, errors.New("error while parsing GitHub App private key: " + err.Error())
+	}
+
+	return &appCredentials{
+		appID:     appID,
+		tokenPath: strings.Replace(InstallationTokenEndpoint, "{installation_id}", installationID, 1),
+		endpoint:  endpoint,
+		version:   version,
+		key:       key,
+		transport: transport,
+		now:       time.Now,
+	}, nil
+}
+
+// parseRSAPrivateKey accepts the PKCS#1 keys generated by GitHub as well as PKCS#8 ones
+func parseRSAPrivateKey(pemKey []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemKey)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA private key")
+	}
+	return key, nil
+}
+
+func (a *appCredentials) token() (string, error) {
+	return a.tokenAt(a.endpoint(""))
+}
+
+// tokenAt exchanges the installation token with the API root given, the /meta probes authenticate before the root is detected
+func (a *appCredentials) tokenAt(root string) (string, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.installationToken != "" && a.now().Before(a.expiresAt.Add(-installationTokenRefreshMargin)) {
+		return a.installationToken, nil
+	}
+
+	jwt, err := a.jwt()
+	if err != nil {
+		return "", errors.New("error while signing GitHub App JWT: " + err.Error())
+	}
+
+	header := http.Header{}
+	header.Set("Accept", GithubJSONMediaType)
+	header.Set(GithubVersionHeader, a.version)
+	header.Set(Authorization, Bearer+jwt)
+	resp, err := a.transport.Do(&Request{Method: http.MethodPost, URL: root + a.tokenPath, Header: header})
+	if err != nil {
+		return "", errors.New("error while getting installation token: " + err.Error())
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return "", errors.New("error while getting installation token: " + strconv.Itoa(resp.StatusCode) + shared.Separator + string(resp.Body))
+	}
+
+	var t installationToken
+	if err = jsoniter.Unmarshal(resp.Body, &t); err != nil {
+		return "", errors.New("error while deserializing installation token: " + err.Error())
+	}
+
+	log.Infof("GitHub App installation token renewed, expires at %s", t.ExpiresAt.Format(time.RFC3339))
+	a.installationToken, a.expiresAt = t.Token, t.ExpiresAt
+	return a.installationToken, nil
+}
+
+// observe drops the cached installation token as soon as GitHub rejects it, e.g. after a revocation
+func (a *appCredentials) observe(token string, resp *Response) {
+	if resp.StatusCode != http.StatusUnauthorized {
+		return
+	}
+
+	a.Lock()
+	defer a.Unlock()
+	if token == a.installationToken {
+		a.installationToken = ""
+	}
+}
+
+func (a *appCredentials) jwt() (string, error) {
+	now := a.now()
+	header, err := jsoniter.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := jsoniter.Marshal(map[string]any{
+		"iat": now.Add(-appJWTClockSkew).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": a.appID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
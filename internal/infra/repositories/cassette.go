@@ -0,0 +1,251 @@
+package repositories
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	RecordCassetteMode = "record"
+	ReplayCassetteMode = "replay"
+
+	redacted = "REDACTED"
+)
+
+// ErrUnmatchedRequest is returned by the replay of a request which wasn't recorded, or not as many times
+var ErrUnmatchedRequest = errors.New("no recorded interaction left for the request")
+
+// Headers, query parameters and JSON fields carrying credentials, never written to the cassettes
+var (
+	sensitiveHeaders = []string{Authorization, "Cookie", "Set-Cookie", "Private-Token", "X-Hub-Signature-256"}
+	sensitiveParams  = []string{"access_token", "client_secret", "token"}
+	sensitiveFields  = map[string]bool{
+		"token": true, "access_token": true, "refresh_token": true, "client_secret": true, "private_key": true, "password": true,
+	}
+)
+
+// The numbers of the bodies are kept as written when a redacted body is serialized again, its keys sorted so that
+// recording the same answer twice gives the same cassette
+var bodyJSON = jsoniter.Config{UseNumber: true, SortMapKeys: true}.Froze()
+
+// Cassette is a recording of the GitHub traffic, the interactions in the order they were sent
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+type Interaction struct {
+	Request  *CassetteRequest  `json:"request"`
+	Response *CassetteResponse `json:"response"`
+}
+
+type CassetteRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type CassetteResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// newCassetteTransport records the traffic of the transport to the cassette file, or replays the cassette instead
+func newCassetteTransport(mode, path string, transport Transport) (Transport, error) {
+	switch mode {
+	case RecordCassetteMode:
+		return NewRecordTransport(path, transport), nil
+	case "", ReplayCassetteMode:
+		return NewReplayTransport(path)
+	default:
+		return nil, errors.New("unknown cassette mode: " + mode)
+	}
+}
+
+// RecordTransport sends the requests through the wrapped transport and keeps every interaction, credentials redacted,
+// the cassette file is written once on Close
+type RecordTransport struct {
+	sync.Mutex
+	path      string
+	transport Transport
+	cassette  *Cassette
+}
+
+func NewRecordTransport(path string, transport Transport) *RecordTransport {
+	return &RecordTransport{path: path, transport: transport, cassette: &Cassette{Interactions: make([]*Interaction, 0)}}
+}
+
+func (t *RecordTransport) Do(req *Request) (*Response, error) {
+	resp, err := t.transport.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	t.cassette.Interactions = append(t.cassette.Interactions, &Interaction{
+		Request: &CassetteRequest{
+			Method: req.Method,
+			URL:    redactURL(req.URL),
+			Header: redactHeader(req.Header),
+			Body:   redactBody(req.Body),
+		},
+		Response: &CassetteResponse{
+			StatusCode: resp.StatusCode,
+			Header:     redactHeader(resp.Header),
+			Body:       redactBody(resp.Body),
+		},
+	})
+	return resp, nil
+}
+
+// Close writes the recorded interactions to the cassette file
+func (t *RecordTransport) Close() error {
+	t.Lock()
+	defer t.Unlock()
+	if err := t.save(); err != nil {
+		return errors.New("couldn't save the cassette " + t.path + ": " + err.Error())
+	}
+	return nil
+}
+
+func (t *RecordTransport) save() error {
+	serialized, err := jsoniter.MarshalIndent(t.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(t.path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(t.path, serialized, 0o600)
+}
+
+// ReplayTransport answers the requests from a cassette without any network access. A request is matched by method,
+// URL and redacted body against the interactions not replayed yet, in the recorded order, the headers are ignored
+type ReplayTransport struct {
+	sync.Mutex
+	cassette *Cassette
+	replayed []bool
+}
+
+func NewReplayTransport(path string) (*ReplayTransport, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cassette := &Cassette{}
+	if err = jsoniter.Unmarshal(content, cassette); err != nil {
+		return nil, errors.New("error while deserializing cassette " + path + ": " + err.Error())
+	}
+	return &ReplayTransport{cassette: cassette, replayed: make([]bool, len(cassette.Interactions))}, nil
+}
+
+func (t *ReplayTransport) Do(req *Request) (*Response, error) {
+	uri := redactURL(req.URL)
+	body := redactBody(req.Body)
+
+	t.Lock()
+	defer t.Unlock()
+	for i, interaction := range t.cassette.Interactions {
+		recorded := interaction.Request
+		if t.replayed[i] || recorded.Method != req.Method || recorded.URL != uri || recorded.Body != body {
+			continue
+		}
+		t.replayed[i] = true
+
+		header := http.Header{}
+		for key, values := range interaction.Response.Header {
+			header[key] = append([]string{}, values...)
+		}
+		return &Response{
+			StatusCode: interaction.Response.StatusCode,
+			Header:     header,
+			Body:       []byte(interaction.Response.Body),
+		}, nil
+	}
+	return nil, errors.New(ErrUnmatchedRequest.Error() + ": " + req.Method + " " + uri)
+}
+
+// Unreplayed returns the number of recorded interactions the test didn't request
+func (t *ReplayTransport) Unreplayed() int {
+	t.Lock()
+	defer t.Unlock()
+	left := 0
+	for _, replayed := range t.replayed {
+		if !replayed {
+			left++
+		}
+	}
+	return left
+}
+
+func redactHeader(header http.Header) http.Header {
+	copied := header.Clone()
+	for _, name := range sensitiveHeaders {
+		if copied.Get(name) != "" {
+			copied.Set(name, redacted)
+		}
+	}
+	return copied
+}
+
+func redactURL(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.RawQuery == "" {
+		return uri
+	}
+	query := parsed.Query()
+	changed := false
+	for _, name := range sensitiveParams {
+		if query.Has(name) {
+			query.Set(name, redacted)
+			changed = true
+		}
+	}
+	if !changed {
+		return uri
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// redactBody replaces the values of the sensitive fields of a JSON body, e.g. the token of a GitHub App installation
+// token exchange, the other bodies are recorded as they are
+func redactBody(body []byte) string {
+	var value any
+	if len(body) == 0 || bodyJSON.Unmarshal(body, &value) != nil || !redactValue(value) {
+		return string(body)
+	}
+	redactedBody, err := bodyJSON.Marshal(value)
+	if err != nil {
+		return redacted
+	}
+	return string(redactedBody)
+}
+
+func redactValue(value any) (changed bool) {
+	switch typed := value.(type) {
+	case map[string]any:
+		for key, field := range typed {
+			if _, ok := field.(string); ok && sensitiveFields[strings.ToLower(key)] {
+				typed[key] = redacted
+				changed = true
+				continue
+			}
+			changed = redactValue(field) || changed
+		}
+	case []any:
+		for _, item := range typed {
+			changed = redactValue(item) || changed
+		}
+	}
+	return changed
+}
@@ -0,0 +1,199 @@
+package repositories
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"scalingo/internal/infra/config"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	s "github.com/stretchr/testify/suite"
+)
+
+type CassetteSuite struct {
+	s.Suite
+	upstream *fakeTransport
+	path     string
+}
+
+func (suite *CassetteSuite) SetupTest() {
+	suite.upstream = &fakeTransport{responses: map[string]*Response{
+		"https://api.github.com/repositories?since=10": {
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Set-Cookie": {"session=secret"}, "X-Ratelimit-Remaining": {"4999"}},
+			Body:       []byte(`[{"id":11,"name":"one","full_name":"john_doe/one","owner":{"login":"john_doe"}}]`),
+		},
+	}}
+	suite.path = filepath.Join(suite.T().TempDir(), "cassettes", "recorded.json")
+}
+
+func (suite *CassetteSuite) TestRecord_RedactsCredentials() {
+	github := &Github{URL: "https://api.github.com/", Token: "secret", UseCredentials: true, Transport: NewRecordTransport(suite.path, suite.upstream)}
+
+	repos, _, err := github.GetRepositories(10)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(repos))
+	assert.NoFileExists(suite.T(), suite.path)
+	assert.NoError(suite.T(), github.Close())
+
+	content, err := os.ReadFile(suite.path)
+	assert.NoError(suite.T(), err)
+	assert.NotContains(suite.T(), string(content), "secret")
+	cassette := &Cassette{}
+	assert.NoError(suite.T(), jsoniter.Unmarshal(content, cassette))
+	assert.Equal(suite.T(), 1, len(cassette.Interactions))
+	assert.Equal(suite.T(), redacted, cassette.Interactions[0].Request.Header.Get(Authorization))
+	assert.Equal(suite.T(), "4999", cassette.Interactions[0].Response.Header.Get("X-Ratelimit-Remaining"))
+}
+
+func (suite *CassetteSuite) TestReplay_Unmatched() {
+	recorder := NewRecordTransport(suite.path, suite.upstream)
+	_, err := recorder.Do(&Request{Method: http.MethodGet, URL: "https://api.github.com/repositories?since=10"})
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), recorder.Close())
+
+	replay, err := NewReplayTransport(suite.path)
+	assert.NoError(suite.T(), err)
+	github := &Github{URL: "https://api.github.com/", Transport: replay}
+
+	repos, _, err := github.GetRepositories(10)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "john_doe/one", repos[0].FullName)
+	assert.Equal(suite.T(), 0, replay.Unreplayed())
+
+	// Every interaction is replayed once
+	_, _, err = github.GetRepositories(10)
+	assert.ErrorContains(suite.T(), err, "no recorded interaction left for the request: GET https://api.github.com/repositories?since=10")
+	_, _, err = github.GetRepositories(20)
+	assert.ErrorContains(suite.T(), err, "no recorded interaction left for the request")
+}
+
+// TestReplay_RedactedBody matches a request body carrying a credential against its redacted recording
+func (suite *CassetteSuite) TestReplay_RedactedBody() {
+	uri := "https://api.github.com/graphql"
+	recorder := NewRecordTransport(suite.path, &fakeTransport{responses: map[string]*Response{
+		uri: {StatusCode: http.StatusOK, Body: []byte(`{"data":{}}`)},
+	}})
+	body := []byte(`{"query":"{ viewer { login } }","access_token":"ghp_secret"}`)
+	_, err := recorder.Do(&Request{Method: http.MethodPost, URL: uri, Body: body})
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), recorder.Close())
+
+	replay, err := NewReplayTransport(suite.path)
+	assert.NoError(suite.T(), err)
+	resp, err := replay.Do(&Request{Method: http.MethodPost, URL: uri, Body: body})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), `{"data":{}}`, string(resp.Body))
+}
+
+func (suite *CassetteSuite) TestRedactURL() {
+	assert.Equal(suite.T(), "https://api.github.com/repositories?access_token=REDACTED&since=10",
+		redactURL("https://api.github.com/repositories?since=10&access_token=ghp_secret"))
+	assert.Equal(suite.T(), "https://api.github.com/repositories?since=10", redactURL("https://api.github.com/repositories?since=10"))
+}
+
+// TestReplay_Pagination replays a recorded crawl: the head from the events, two pages of repositories then an empty one,
+// a languages endpoint moved to the repository ID and a missing one
+func (suite *CassetteSuite) TestReplay_Pagination() {
+	replay, err := NewReplayTransport(filepath.Join("testdata", "cassettes", "github_pagination.json"))
+	assert.NoError(suite.T(), err)
+	github := &Github{URL: "https://api.github.com/", LatestCreatedRepoRetry: 1, MaxRedirects: 3, Transport: replay}
+
+	id, err := github.GetLatestRepoID()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 755000150, id)
+
+	listed := 0
+	for since := id; ; {
+		page, _, err := github.GetRepositories(since)
+		assert.NoError(suite.T(), err)
+		if len(page) == 0 {
+			break
+		}
+		listed += len(page)
+		since = page[len(page)-1].ID
+	}
+	assert.Equal(suite.T(), 42, listed) // 49 repositories, 7 forks
+
+	languages, err := github.GetRepositoryLanguages("https://api.github.com/repos/user92251/engine-app-51/languages")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), map[string]int{"Go": 1200, "Shell": 30}, languages)
+
+	languages, err = github.GetRepositoryLanguages("https://api.github.com/repos/user47621/parser-demo-52/languages")
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), languages)
+	assert.Equal(suite.T(), 0, replay.Unreplayed())
+}
+
+// TestRecord_AppInstallationToken records a GitHub App session: the token exchange stays out of the cassette
+// and a recorded exchange would have its token redacted anyway
+func (suite *CassetteSuite) TestRecord_AppInstallationToken() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(suite.T(), err)
+	keyFile := filepath.Join(suite.T().TempDir(), "app.pem")
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0o600)
+	assert.NoError(suite.T(), err)
+	exchange := `{"token":"ghs_live","expires_at":"` + time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `","permissions":{"metadata":"read"}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/app/installations/42/access_tokens" {
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(exchange))
+			return
+		}
+		_, _ = w.Write([]byte(`{"Go":42}`))
+	}))
+	defer server.Close()
+
+	github := ProvideGithub(&config.Config{
+		GitHubURL:               server.URL + "/",
+		GitHubTransport:         NetHTTPTransport,
+		GitHubAppID:             "1234",
+		GitHubAppInstallationID: "42",
+		GitHubAppPrivateKeyFile: keyFile,
+		GitHubCassette:          suite.path,
+		GitHubCassetteMode:      RecordCassetteMode,
+	})
+	_, err = github.GetRepositoryLanguages(server.URL + "/repos/john_doe/one/languages")
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), github.Close())
+
+	content, err := os.ReadFile(suite.path)
+	assert.NoError(suite.T(), err)
+	cassette := &Cassette{}
+	assert.NoError(suite.T(), jsoniter.Unmarshal(content, cassette))
+	assert.Equal(suite.T(), 1, len(cassette.Interactions))
+	assert.NotContains(suite.T(), string(content), "ghs_live")
+
+	recorder := NewRecordTransport(suite.path, &fakeTransport{responses: map[string]*Response{
+		server.URL + "/app/installations/42/access_tokens": {StatusCode: http.StatusCreated, Body: []byte(exchange)},
+	}})
+	_, err = recorder.Do(&Request{Method: http.MethodPost, URL: server.URL + "/app/installations/42/access_tokens"})
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), recorder.Close())
+	content, err = os.ReadFile(suite.path)
+	assert.NoError(suite.T(), err)
+	assert.NotContains(suite.T(), string(content), "ghs_live")
+	assert.Contains(suite.T(), recorder.cassette.Interactions[0].Response.Body, `"token":"REDACTED"`)
+	assert.Contains(suite.T(), recorder.cassette.Interactions[0].Response.Body, `"metadata":"read"`)
+}
+
+func (suite *CassetteSuite) TestRedactBody() {
+	assert.Equal(suite.T(), `[{"id":755000150,"name":"one"}]`, redactBody([]byte(`[{"id":755000150,"name":"one"}]`)))
+	assert.Equal(suite.T(), `{"data":{"client_secret":"REDACTED","size":12345678901234567}}`,
+		redactBody([]byte(`{"data":{"client_secret":"s3cr3t","size":12345678901234567}}`)))
+	assert.Equal(suite.T(), "not json", redactBody([]byte("not json")))
+}
+
+func TestCassetteSuite(t *testing.T) {
+	s.Run(t, new(CassetteSuite))
+}
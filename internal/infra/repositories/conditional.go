@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"container/list"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	ETagHeader            = "ETag"
+	LastModifiedHeader    = "Last-Modified"
+	IfNoneMatchHeader     = "If-None-Match"
+	IfModifiedSinceHeader = "If-Modified-Since"
+)
+
+type conditionalEntry struct {
+	uri          string
+	etag         string
+	lastModified string
+	body         []byte
+}
+
+// Concurrent safe LRU store of the validators and body of the last successful answer for every URL,
+// GitHub doesn't count 304 answers against the rate limit so revalidating is almost free
+type conditionalCache struct {
+	sync.Mutex
+	entries    map[string]*list.Element
+	order      *list.List // most recently used first
+	maxEntries int
+}
+
+func newConditionalCache(maxEntries int) *conditionalCache {
+	return &conditionalCache{
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		maxEntries: maxEntries,
+	}
+}
+
+// cacheable leaves out the pages of the repository listing, they follow the moving head and are seldom requested twice
+func (c *conditionalCache) cacheable(uri string) bool {
+	parsed, err := url.Parse(uri)
+	return err == nil && !strings.HasSuffix(parsed.Path, "/"+RepoListEndpoint)
+}
+
+// prepare adds the validators known for the URL to the request headers
+func (c *conditionalCache) prepare(uri string, header http.Header) {
+	c.Lock()
+	element, ok := c.entries[uri]
+	if ok {
+		c.order.MoveToFront(element)
+	}
+	c.Unlock()
+	if !ok {
+		return
+	}
+
+	entry := element.Value.(*conditionalEntry)
+	if entry.etag != "" {
+		header.Set(IfNoneMatchHeader, entry.etag)
+	}
+	if entry.lastModified != "" {
+		header.Set(IfModifiedSinceHeader, entry.lastModified)
+	}
+}
+
+// resolve swaps a 304 answer for the cached body and stores the validators of a fresh 200 answer,
+// false when the 304 can't be resolved since the entry was evicted meanwhile
+func (c *conditionalCache) resolve(uri string, resp *Response) (*Response, bool) {
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		c.Lock()
+		element, ok := c.entries[uri]
+		c.Unlock()
+		if !ok {
+			return resp, false
+		}
+		return &Response{StatusCode: http.StatusOK, Header: resp.Header, Body: element.Value.(*conditionalEntry).body, NotModified: true}, true
+	case http.StatusOK:
+		etag, lastModified := resp.Header.Get(ETagHeader), resp.Header.Get(LastModifiedHeader)
+		if (etag == "" && lastModified == "") || !c.cacheable(uri) {
+			return resp, true
+		}
+		c.set(&conditionalEntry{uri: uri, etag: etag, lastModified: lastModified, body: resp.Body})
+	}
+	return resp, true
+}
+
+func (c *conditionalCache) set(entry *conditionalEntry) {
+	c.Lock()
+	defer c.Unlock()
+
+	if element, ok := c.entries[entry.uri]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[entry.uri] = c.order.PushFront(entry)
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*conditionalEntry).uri)
+	}
+}
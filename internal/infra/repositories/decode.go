@@ -0,0 +1,185 @@
+package repositories
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"scalingo/internal/core/dto"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// GitHub pages hold up to 100 items
+const pageSize = 100
+
+// streamedRepository holds the only fields of a /repositories item the service uses,
+// jsoniter matches the fields of such small structs by hash and skips the others without allocating them
+type streamedRepository struct {
+	ID           int           `json:"id"`
+	NodeID       string        `json:"node_id"`
+	Name         string        `json:"name"`
+	FullName     string        `json:"full_name"`
+	Owner        streamedOwner `json:"owner"`
+	LanguagesURL string        `json:"languages_url"`
+	HTMLURL      string        `json:"html_url"`
+	URL          string        `json:"url"`
+	Description  *string       `json:"description"` // null for the repositories without description
+	Fork         bool          `json:"fork"`
+}
+
+type streamedOwner struct {
+	Login string `json:"login"`
+}
+
+type streamedEvent struct {
+	Type string `json:"type"`
+	Repo struct {
+		ID int `json:"id"`
+	} `json:"repo"`
+	Payload struct {
+		RefType *string `json:"ref_type"`
+	} `json:"payload"`
+}
+
+// decodeRepositories decodes the buffered /repositories body item by item into the reduced struct, the forks being
+// dropped as soon as they are decoded. The answer is still read whole, only the allocations of the unused fields are
+// saved (about 42 KB instead of 61 KB per page in the benchmark). next is the highest ID of the page forks included
+func decodeRepositories(body []byte) (repos []*dto.LatestCreatedRepo, next int, err error) {
+	iter := jsoniter.ConfigFastest.BorrowIterator(body)
+	defer jsoniter.ConfigFastest.ReturnIterator(iter)
+
+	repos = make([]*dto.LatestCreatedRepo, 0, pageSize)
+	var repository streamedRepository
+	for iter.ReadArray() {
+		repository = streamedRepository{}
+		iter.ReadVal(&repository)
+		if iter.Error != nil {
+			break
+		}
+		next = max(next, repository.ID)
+		if repository.Fork { // excluding forks
+			continue
+		}
+
+		repo := &dto.LatestCreatedRepo{
+			ID:           repository.ID,
+			NodeID:       repository.NodeID,
+			Name:         repository.Name,
+			FullName:     repository.FullName,
+			Owner:        &dto.Owner{Login: repository.Owner.Login},
+			URL:          repository.URL,
+			LanguagesURL: repository.LanguagesURL,
+			HTMLURL:      repository.HTMLURL,
+		}
+		if repository.Description != nil {
+			repo.Description = *repository.Description
+		}
+		repos = append(repos, repo)
+	}
+	if iter.Error != nil && !errors.Is(iter.Error, io.EOF) {
+		return nil, 0, iter.Error
+	}
+	return repos, next, nil
+}
+
+// decodeLatestCreatedRepoID decodes the /events array until the first repository CreateEvent
+func decodeLatestCreatedRepoID(body []byte) (id int, found bool, err error) {
+	iter := jsoniter.ConfigFastest.BorrowIterator(body)
+	defer jsoniter.ConfigFastest.ReturnIterator(iter)
+
+	var event streamedEvent
+	for iter.ReadArray() {
+		event = streamedEvent{}
+		iter.ReadVal(&event)
+		if iter.Error != nil {
+			break
+		}
+		if event.Type == CreateEvent && event.Payload.RefType != nil && *event.Payload.RefType == RepositoryRefType {
+			return event.Repo.ID, true, nil
+		}
+	}
+	if iter.Error != nil && !errors.Is(iter.Error, io.EOF) {
+		return 0, false, iter.Error
+	}
+	return 0, false, nil
+}
+
+// decodeMaxRepositoryID decodes only the IDs of a /repositories page, forks included, and returns the highest one
+func decodeMaxRepositoryID(body []byte) (maxID, count int, err error) {
+	iter := jsoniter.ConfigFastest.BorrowIterator(body)
+	defer jsoniter.ConfigFastest.ReturnIterator(iter)
+
+	var repository struct {
+		ID int `json:"id"`
+	}
+	for iter.ReadArray() {
+		repository.ID = 0
+		iter.ReadVal(&repository)
+		if iter.Error != nil {
+			break
+		}
+		maxID = max(maxID, repository.ID)
+		count++
+	}
+	if iter.Error != nil && !errors.Is(iter.Error, io.EOF) {
+		return 0, 0, iter.Error
+	}
+	return maxID, count, nil
+}
+
+type streamedFullEvent struct {
+	ID    string `json:"id"`
+	Type  string `json:"type"`
+	Actor struct {
+		Login string `json:"login"`
+	} `json:"actor"`
+	Repo struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"repo"`
+	Payload struct {
+		Action  *string `json:"action"`
+		Ref     *string `json:"ref"`
+		RefType *string `json:"ref_type"`
+	} `json:"payload"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// decodeEvents decodes an /events page, newest first, skipping the bulky payloads (commits, issues, pull requests...)
+func decodeEvents(body []byte) ([]*dto.Event, error) {
+	iter := jsoniter.ConfigFastest.BorrowIterator(body)
+	defer jsoniter.ConfigFastest.ReturnIterator(iter)
+
+	events := make([]*dto.Event, 0, pageSize)
+	var event streamedFullEvent
+	for iter.ReadArray() {
+		event = streamedFullEvent{}
+		iter.ReadVal(&event)
+		if iter.Error != nil {
+			break
+		}
+		events = append(events, &dto.Event{
+			ID:        event.ID,
+			Type:      event.Type,
+			Actor:     event.Actor.Login,
+			RepoID:    event.Repo.ID,
+			RepoName:  event.Repo.Name,
+			Action:    deref(event.Payload.Action),
+			Ref:       deref(event.Payload.Ref),
+			RefType:   deref(event.Payload.RefType),
+			CreatedAt: event.CreatedAt,
+		})
+	}
+	if iter.Error != nil && !errors.Is(iter.Error, io.EOF) {
+		return nil, iter.Error
+	}
+	return events, nil
+}
+
+func deref(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
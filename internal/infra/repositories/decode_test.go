@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"os"
+	"testing"
+
+	"scalingo/internal/core/dto"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+)
+
+// legacyRepository is the full struct the repositories used to be unmarshalled into, kept as the benchmark baseline
+type legacyRepository struct {
+	ID           int        `json:"id"`
+	NodeID       string     `json:"node_id"`
+	Name         string     `json:"name"`
+	FullName     string     `json:"full_name"`
+	Owner        *dto.Owner `json:"owner"`
+	LanguagesURL string     `json:"languages_url"`
+	HTMLURL      string     `json:"html_url"`
+	URL          string     `json:"url"`
+	Description  any        `json:"description"`
+	Fork         bool       `json:"fork"`
+}
+
+func unmarshalRepositories(body []byte) ([]*dto.LatestCreatedRepo, error) {
+	repositories := make([]*legacyRepository, 0)
+	if err := jsoniter.Unmarshal(body, &repositories); err != nil {
+		return nil, err
+	}
+
+	repos := make([]*dto.LatestCreatedRepo, 0)
+	for _, repository := range repositories {
+		var description string
+		if repository.Description != nil {
+			description = repository.Description.(string)
+		}
+		if !repository.Fork {
+			repos = append(repos, &dto.LatestCreatedRepo{
+				ID:           repository.ID,
+				NodeID:       repository.NodeID,
+				Name:         repository.Name,
+				FullName:     repository.FullName,
+				Owner:        &dto.Owner{Login: repository.Owner.Login},
+				URL:          repository.URL,
+				LanguagesURL: repository.LanguagesURL,
+				HTMLURL:      repository.HTMLURL,
+				Description:  description,
+			})
+		}
+	}
+	return repos, nil
+}
+
+func readTestdata(tb testing.TB, name string) []byte {
+	tb.Helper()
+	body, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return body
+}
+
+func TestDecodeRepositories(t *testing.T) {
+	body := readTestdata(t, "repositories.json")
+
+	streamed, next, err := decodeRepositories(body)
+	assert.NoError(t, err)
+	unmarshalled, err := unmarshalRepositories(body)
+	assert.NoError(t, err)
+
+	assert.Equal(t, unmarshalled, streamed)
+	assert.Equal(t, 86, len(streamed))
+	assert.Equal(t, 755000199, next)
+
+	_, _, err = decodeRepositories([]byte(`{"message":"Not Found"}`))
+	assert.Error(t, err)
+
+	_, _, err = decodeRepositories([]byte(`[{"id":1,"fork":false},{"id":"two"},{"id":3,"fork":false}]`))
+	assert.Error(t, err)
+}
+
+func TestDecodeLatestCreatedRepoID(t *testing.T) {
+	id, found, err := decodeLatestCreatedRepoID(readTestdata(t, "events.json"))
+
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 755000150, id)
+}
+
+func BenchmarkDecodeRepositories_Unmarshal(b *testing.B) {
+	body := readTestdata(b, "repositories.json")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := unmarshalRepositories(body); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeRepositories_Stream(b *testing.B) {
+	body := readTestdata(b, "repositories.json")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := decodeRepositories(body); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
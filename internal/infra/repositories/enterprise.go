@@ -0,0 +1,174 @@
+package repositories
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"scalingo/internal/core/port"
+	conf "scalingo/internal/infra/config"
+
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	MetaEndpoint = "meta"
+
+	// GitHub Enterprise Server REST API root, the GraphQL API lives next to it under /api/graphql
+	EnterpriseAPIPath = "/api/v3/"
+
+	githubAPIHost = "api.github.com"
+
+	// First GitHub Enterprise Server release supporting the X-GitHub-Api-Version header
+	apiVersionHeaderSince = "3.9"
+)
+
+type serverMeta struct {
+	InstalledVersion string `json:"installed_version"`
+}
+
+func (m *serverMeta) enterprise() bool {
+	return m.InstalledVersion != ""
+}
+
+// ProvideSources registers the main GitHub adapter along with the GitHub Enterprise Server hosts configured side by side
+// and the GitLab and Gitea instances
+func ProvideSources(config *conf.Config, github port.GithubInterface, gitlab *Gitlab, giteas []*Gitea) port.Sources {
+	sources := port.Sources{port.DefaultSource: github}
+	if gitlab != nil {
+		sources[gitlab.Name] = gitlab
+	}
+	for _, gitea := range giteas {
+		sources[gitea.Name] = gitea
+	}
+
+	for name, url := range config.GitHubEnterpriseURLs {
+		enterprise := newGithub(config, name, url)
+		if token, ok := config.GitHubEnterpriseTokens[name]; ok {
+			enterprise.Token = token
+			enterprise.UseCredentials = true
+			enterprise.tokens = newTokenPool([]string{token})
+			enterprise.credentials = enterprise.tokens
+		}
+		sources[name] = newGithubAdapter(config.GitHubAPI, "", enterprise)
+	}
+	return sources
+}
+
+// normalizeBaseURL makes sure the endpoints can be appended to the API root
+func normalizeBaseURL(url string) string {
+	if url == "" || strings.HasSuffix(url, "/") {
+		return url
+	}
+	return url + "/"
+}
+
+// deriveGraphQLURL derives the GraphQL endpoint from the REST one, https://host/api/v3/ is served by https://host/api/graphql
+func deriveGraphQLURL(restURL string) string {
+	if strings.HasSuffix(restURL, EnterpriseAPIPath) {
+		return strings.TrimSuffix(restURL, "v3/") + GraphQLEndpoint
+	}
+	return restURL + GraphQLEndpoint
+}
+
+// detectServer queries /meta once to tell GitHub Enterprise Server hosts apart from github.com,
+// the releases older than 3.9 reject the X-GitHub-Api-Version header so it is dropped for them.
+// A host-only GITHUB_URL of an enterprise server is moved to its API root under /api/v3/
+func (g *Github) detectServer() {
+	if !g.DetectVersion {
+		return
+	}
+
+	meta, err := g.fetchMeta(g.URL)
+	if err != nil && hostOnly(g.URL) {
+		// The host root of GitHub Enterprise Server answers the web UI
+		root := strings.TrimSuffix(g.URL, "/") + EnterpriseAPIPath
+		if enterpriseMeta, enterpriseErr := g.fetchMeta(root); enterpriseErr == nil && enterpriseMeta.enterprise() {
+			meta, err = enterpriseMeta, nil
+			g.rebase(root)
+		}
+	}
+	if err != nil {
+		log.Warnf("couldn't detect the version of %s, keeping the defaults: %s", g.URL, err.Error())
+		return
+	}
+
+	if meta.enterprise() {
+		log.Infof("GitHub Enterprise Server %s detected for %s", meta.InstalledVersion, g.URL)
+		if versionLess(meta.InstalledVersion, apiVersionHeaderSince) {
+			g.Version = ""
+		}
+	}
+}
+
+// fetchMeta authenticates the probe, the servers in private mode answer /meta to the signed in users only
+func (g *Github) fetchMeta(root string) (*serverMeta, error) {
+	header := http.Header{}
+	var token string
+	if g.UseCredentials {
+		var err error
+		if token, err = g.metaBearer(root); err != nil {
+			return nil, err
+		}
+		header.Set(Authorization, Bearer+token)
+	}
+	resp, err := g.roundTrip(&Request{Method: http.MethodGet, URL: root + MetaEndpoint, Header: header}, token)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("error while getting meta: " + strconv.Itoa(resp.StatusCode))
+	}
+
+	meta := &serverMeta{}
+	if err = jsoniter.Unmarshal(resp.Body, meta); err != nil {
+		return nil, errors.New("error while deserializing meta: " + err.Error())
+	}
+	return meta, nil
+}
+
+// metaBearer resolves the token while the API root is being detected, the installation tokens of a GitHub App
+// are exchanged with the probed root instead of the detected one
+func (g *Github) metaBearer(root string) (string, error) {
+	if app, ok := g.credentials.(*appCredentials); ok {
+		return app.tokenAt(root)
+	}
+	return g.bearer()
+}
+
+// rebase moves the API root, the guard keeps allowing the previous one along with the new REST and GraphQL roots
+func (g *Github) rebase(root string) {
+	log.Infof("GitHub Enterprise Server API of %s found under %s", g.URL, root)
+	g.URL = root
+	if g.guard != nil {
+		g.guard.allow(root)
+		g.guard.allow(deriveGraphQLURL(root))
+	}
+}
+
+// hostOnly tells the API roots without path other than github.com, e.g. https://ghe.corp/
+func hostOnly(root string) bool {
+	parsed, err := url.Parse(root)
+	return err == nil && strings.Trim(parsed.Path, "/") == "" && !strings.EqualFold(parsed.Hostname(), githubAPIHost)
+}
+
+// versionLess compares dotted numeric versions such as 3.8.12 and 3.9
+func versionLess(version, than string) bool {
+	left, right := strings.Split(version, "."), strings.Split(than, ".")
+	for i := 0; i < len(left) || i < len(right); i++ {
+		var l, r int
+		if i < len(left) {
+			l, _ = strconv.Atoi(left[i])
+		}
+		if i < len(right) {
+			r, _ = strconv.Atoi(right[i])
+		}
+		if l != r {
+			return l < r
+		}
+	}
+	return false
+}
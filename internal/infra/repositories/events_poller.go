@@ -0,0 +1,269 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"scalingo/internal/core/dto"
+	"scalingo/internal/core/port"
+	conf "scalingo/internal/infra/config"
+	"scalingo/internal/shared"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	PollIntervalHeader = "X-Poll-Interval"
+	LinkHeader         = "Link"
+
+	PerPage = "?per_page=100"
+)
+
+var nextLinkPattern = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// eventRing is a fixed size buffer of the most recent events, oldest first, the oldest ones are overwritten when it is full
+type eventRing struct {
+	events []*dto.Event
+	start  int
+	count  int
+}
+
+func newEventRing(capacity int) *eventRing {
+	return &eventRing{events: make([]*dto.Event, max(capacity, 1))}
+}
+
+// push appends the event and returns the one it overwrote, if any
+func (r *eventRing) push(event *dto.Event) (evicted *dto.Event) {
+	end := (r.start + r.count) % len(r.events)
+	if r.count == len(r.events) {
+		evicted = r.events[r.start]
+		r.start = (r.start + 1) % len(r.events)
+	} else {
+		r.count++
+	}
+	r.events[end] = event
+	return evicted
+}
+
+func (r *eventRing) list() []*dto.Event {
+	events := make([]*dto.Event, 0, r.count)
+	for i := 0; i < r.count; i++ {
+		events = append(events, r.events[(r.start+i)%len(r.events)])
+	}
+	return events
+}
+
+// EventsPoller follows the public events feed in the background. It waits at least the X-Poll-Interval GitHub asks for,
+// revalidates the first page with its ETag and pages through the Link headers until it catches up with the known events
+type EventsPoller struct {
+	sync.RWMutex
+	github   *Github
+	interval time.Duration
+	maxPages int
+
+	ring                *eventRing
+	seen                map[string]struct{}
+	latestCreatedRepoID int
+	subscribers         map[int]chan *dto.Event
+	nextSubscriber      int
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// ProvideEventsPoller returns the poller of the GitHub adapter, nil when the polling is disabled or GitHub is offline
+func ProvideEventsPoller(config *conf.Config, github *Github) *EventsPoller {
+	if !config.GitHubEventsPolling || config.GitHubMode == OfflineMode {
+		return nil
+	}
+	if github.events == nil {
+		github.events = NewEventsPoller(github, config.GitHubEventsPollInterval, config.GitHubEventsMaxPages, config.GitHubEventsBufferSize)
+	}
+	return github.events
+}
+
+// ProvideEventSource exposes the poller to the core, or the recorded events when GitHub is offline,
+// as a nil interface when the polling is disabled
+func ProvideEventSource(poller *EventsPoller, offline *Offline) port.EventSourceInterface {
+	if offline != nil {
+		return offline
+	}
+	if poller == nil {
+		return nil
+	}
+	return poller
+}
+
+func NewEventsPoller(github *Github, interval time.Duration, maxPages, bufferSize int) *EventsPoller {
+	return &EventsPoller{
+		github:      github,
+		interval:    interval,
+		maxPages:    maxPages,
+		ring:        newEventRing(bufferSize),
+		seen:        make(map[string]struct{}),
+		subscribers: make(map[int]chan *dto.Event),
+	}
+}
+
+func (p *EventsPoller) Start(ctx context.Context) {
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.done = make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+		for {
+			wait, err := p.Poll()
+			if err != nil {
+				log.Errorf("Events polling error: %s", err.Error())
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+	}()
+}
+
+func (p *EventsPoller) Stop() {
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+	<-p.done
+}
+
+// Poll fetches the events published since the previous poll and returns the delay before the next one
+func (p *EventsPoller) Poll() (time.Duration, error) {
+	wait := p.interval
+	fresh := make([]*dto.Event, 0)
+
+	url := p.github.endpoint(EventsEndpoint) + PerPage
+	for page := 0; url != "" && page < p.maxPages; page++ {
+		resp, err := p.github.send(http.MethodGet, url, nil)
+		if err != nil {
+			return wait, errors.New("error while polling events: " + err.Error())
+		}
+		if resp.StatusCode != http.StatusOK {
+			return wait, errors.New("error while polling events: " + strconv.Itoa(resp.StatusCode) + shared.Separator + string(resp.Body))
+		}
+		if page == 0 {
+			if seconds, err := strconv.Atoi(resp.Header.Get(PollIntervalHeader)); err == nil {
+				wait = max(wait, time.Duration(seconds)*time.Second)
+			}
+			if resp.NotModified {
+				// Nothing new since the previous poll and the revalidation didn't cost any quota
+				break
+			}
+		}
+
+		events, err := decodeEvents(resp.Body)
+		if err != nil {
+			return wait, errors.New("error while deserializing events: " + err.Error())
+		}
+
+		caughtUp := false
+		for _, event := range events {
+			if p.known(event.ID) {
+				caughtUp = true
+				break
+			}
+			fresh = append(fresh, event)
+		}
+		if caughtUp {
+			break
+		}
+		url = nextLink(resp.Header.Get(LinkHeader))
+	}
+
+	p.record(fresh)
+	return wait, nil
+}
+
+func (p *EventsPoller) known(id string) bool {
+	p.RLock()
+	defer p.RUnlock()
+	_, ok := p.seen[id]
+	return ok
+}
+
+// record buffers the events, received newest first, in chronological order and publishes them to the subscribers
+func (p *EventsPoller) record(fresh []*dto.Event) {
+	p.Lock()
+	defer p.Unlock()
+
+	for i := len(fresh) - 1; i >= 0; i-- {
+		event := fresh[i]
+		if _, ok := p.seen[event.ID]; ok {
+			continue
+		}
+		p.seen[event.ID] = struct{}{}
+		if evicted := p.ring.push(event); evicted != nil {
+			delete(p.seen, evicted.ID)
+		}
+
+		if event.Type == CreateEvent && event.RefType == RepositoryRefType {
+			p.latestCreatedRepoID = max(p.latestCreatedRepoID, event.RepoID)
+		}
+
+		for id, subscriber := range p.subscribers {
+			select {
+			case subscriber <- event:
+			default:
+				log.Warnf("Events subscriber %d too slow, event %s dropped", id, event.ID)
+			}
+		}
+	}
+	if len(fresh) > 0 {
+		log.Infof("%d new events polled", len(fresh))
+	}
+}
+
+// Recent returns the buffered events, oldest first
+func (p *EventsPoller) Recent() []*dto.Event {
+	p.RLock()
+	defer p.RUnlock()
+	return p.ring.list()
+}
+
+// LatestCreatedRepoID returns the ID of the latest repository CreateEvent polled
+func (p *EventsPoller) LatestCreatedRepoID() (int, bool) {
+	p.RLock()
+	defer p.RUnlock()
+	return p.latestCreatedRepoID, p.latestCreatedRepoID != 0
+}
+
+// Subscribe returns a channel receiving the events as they are polled, the events are dropped
+// when the subscriber doesn't keep up with its buffer. The returned function unsubscribes
+func (p *EventsPoller) Subscribe(buffer int) (events <-chan *dto.Event, unsubscribe func()) {
+	p.Lock()
+	defer p.Unlock()
+
+	id := p.nextSubscriber
+	p.nextSubscriber++
+	subscriber := make(chan *dto.Event, buffer)
+	p.subscribers[id] = subscriber
+
+	return subscriber, func() {
+		p.Lock()
+		defer p.Unlock()
+		if _, ok := p.subscribers[id]; ok {
+			delete(p.subscribers, id)
+			close(subscriber)
+		}
+	}
+}
+
+func nextLink(link string) string {
+	match := nextLinkPattern.FindStringSubmatch(link)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
@@ -0,0 +1,274 @@
+package repositories
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"scalingo/internal/core/dto"
+	conf "scalingo/internal/infra/config"
+	"scalingo/internal/shared"
+
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	GiteaSearchEndpoint = "repos/search"
+	GiteaReposPath      = "repos/"
+	GiteaTokenPrefix    = "token "
+
+	// Newest repositories first, Gitea pages hold up to 50 items
+	giteaSearchQuery = "?sort=created&order=desc&limit=50&page="
+	// Bound of the pages walked back to the last listed repository
+	giteaMaxPages = 10
+)
+
+// Gitea is the repository source of a Gitea API instance, Gitea, Forgejo or Codeberg, built on its REST API v1
+type Gitea struct {
+	Name      string
+	URL       string
+	Token     string
+	Transport Transport
+
+	guard *urlGuard
+}
+
+type giteaSearch struct {
+	OK   bool              `json:"ok"`
+	Data []giteaRepository `json:"data"`
+}
+
+type giteaRepository struct {
+	ID           int           `json:"id"`
+	Name         string        `json:"name"`
+	FullName     string        `json:"full_name"`
+	Owner        streamedOwner `json:"owner"`
+	HTMLURL      string        `json:"html_url"`
+	URL          string        `json:"url"`
+	LanguagesURL string        `json:"languages_url"`
+	Description  string        `json:"description"`
+	Fork         bool          `json:"fork"`
+	Licenses     []string      `json:"licenses"` // detected since Gitea 1.22, absent from the older releases
+}
+
+// ProvideGiteas builds the Gitea sources of the instances configured by name, e.g. codeberg=https://codeberg.org/api/v1/
+func ProvideGiteas(config *conf.Config) []*Gitea {
+	giteas := make([]*Gitea, 0, len(config.GiteaURLs))
+	for name, url := range config.GiteaURLs {
+		giteas = append(giteas, newGitea(config, name, url, config.GiteaTokens[name]))
+	}
+	return giteas
+}
+
+func newGitea(config *conf.Config, name, url, token string) *Gitea {
+	transport, err := newTransport(config)
+	if err != nil {
+		panic("Error initializing Gitea transport: " + err.Error())
+	}
+
+	gitea := &Gitea{
+		Name:      name,
+		URL:       normalizeBaseURL(url),
+		Token:     token,
+		Transport: transport,
+	}
+	gitea.guard = newURLGuard(gitea.URL)
+	return gitea
+}
+
+// GetLatestRepoID returns the ID preceding the newest page of repositories, the first listing then returns that page
+func (g *Gitea) GetLatestRepoID() (int, error) {
+	repos, err := g.search(1)
+	if err != nil {
+		return 0, errors.New("error while getting latest repo id: " + err.Error())
+	}
+	if len(repos) == 0 {
+		return 0, nil
+	}
+
+	lowest := repos[0].ID
+	for _, repo := range repos {
+		lowest = min(lowest, repo.ID)
+	}
+	return lowest - 1, nil
+}
+
+// GetRepositories lists the repositories created after the given ID, oldest first, walking the newest pages back to it,
+// only the oldest page of them when they are more than the walked pages hold
+func (g *Gitea) GetRepositories(id int) ([]*dto.LatestCreatedRepo, int, error) {
+	var found []giteaRepository
+	for page := 1; page <= giteaMaxPages; page++ {
+		current, err := g.search(page)
+		if err != nil {
+			return nil, id, errors.New("error while getting repositories: " + err.Error())
+		}
+		found = append(found, current...)
+		if reaches(current, id) {
+			repos, next := g.listed(found, id)
+			return repos, next, nil
+		}
+	}
+
+	// More repositories were created since id than the walked pages hold, only the oldest page of them is listed
+	// so that the next listings resume from it instead of skipping the ones in between
+	oldest, err := g.oldestPageSince(id)
+	if err != nil {
+		return nil, id, errors.New("error while getting repositories: " + err.Error())
+	}
+	repos, next := g.listed(oldest, id)
+	return repos, next, nil
+}
+
+// listed keeps the repositories created after id, forks excluded, oldest first, along with the highest ID forks included
+func (g *Gitea) listed(found []giteaRepository, id int) ([]*dto.LatestCreatedRepo, int) {
+	repos := make([]*dto.LatestCreatedRepo, 0, len(found))
+	next := id
+	for i := range found {
+		repository := &found[i]
+		if repository.ID <= id {
+			continue
+		}
+		next = max(next, repository.ID)
+		if repository.Fork { // excluding forks
+			continue
+		}
+		repos = append(repos, g.toLatestCreatedRepo(repository))
+	}
+
+	slices.SortFunc(repos, func(a, b *dto.LatestCreatedRepo) int { return a.ID - b.ID })
+	return repos, next
+}
+
+// oldestPageSince gallops then bisects the pages beyond the walked ones for the first one reaching id, and returns
+// it when it still holds newer repositories, the page before it otherwise
+func (g *Gitea) oldestPageSince(id int) ([]giteaRepository, error) {
+	low, high := giteaMaxPages, 2*giteaMaxPages
+	var reaching []giteaRepository
+	for {
+		page, err := g.search(high)
+		if err != nil {
+			return nil, err
+		}
+		if reaches(page, id) {
+			reaching = page
+			break
+		}
+		low, high = high, 2*high
+	}
+
+	for high-low > 1 {
+		middle := low + (high-low)/2
+		page, err := g.search(middle)
+		if err != nil {
+			return nil, err
+		}
+		if reaches(page, id) {
+			high, reaching = middle, page
+		} else {
+			low = middle
+		}
+	}
+
+	if slices.ContainsFunc(reaching, func(repository giteaRepository) bool { return repository.ID > id }) {
+		return reaching, nil
+	}
+	return g.search(high - 1)
+}
+
+// reaches tells whether the page goes back to id, or past the oldest repository when empty
+func reaches(page []giteaRepository, id int) bool {
+	return len(page) == 0 || slices.ContainsFunc(page, func(repository giteaRepository) bool { return repository.ID <= id })
+}
+
+func (g *Gitea) search(page int) ([]giteaRepository, error) {
+	statusCode, body, err := g.httpRequest(g.URL + GiteaSearchEndpoint + giteaSearchQuery + strconv.Itoa(page))
+	if err != nil {
+		return nil, errors.New(strconv.Itoa(statusCode) + shared.Separator + err.Error())
+	}
+
+	var result giteaSearch
+	if err = jsoniter.Unmarshal(body, &result); err != nil {
+		return nil, errors.New("error while deserializing repositories: " + strconv.Itoa(statusCode) + shared.Separator + err.Error())
+	}
+	if !result.OK {
+		return nil, errors.New("search failed: " + strconv.Itoa(statusCode))
+	}
+	return result.Data, nil
+}
+
+func (g *Gitea) toLatestCreatedRepo(repository *giteaRepository) *dto.LatestCreatedRepo {
+	repoURL := g.URL + GiteaReposPath + repository.FullName
+	return &dto.LatestCreatedRepo{
+		ID:           repository.ID,
+		Name:         repository.Name,
+		FullName:     repository.FullName,
+		Owner:        &dto.Owner{Login: repository.Owner.Login},
+		HTMLURL:      repository.HTMLURL,
+		LanguagesURL: repoURL + "/languages",
+		URL:          repoURL,
+		Description:  repository.Description,
+	}
+}
+
+func (g *Gitea) GetRepositoryLanguages(fullURL string) (map[string]int, error) {
+	statusCode, body, err := g.httpRequest(fullURL)
+	if err != nil {
+		return map[string]int{},
+			errors.New("error while getting repository languages: " + strconv.Itoa(statusCode) + shared.Separator + err.Error())
+	}
+	if statusCode == http.StatusNotFound {
+		log.Warnf("Language not found for %s, skipping...", fullURL)
+		return map[string]int{}, nil
+	}
+
+	var languages map[string]int
+	if err = jsoniter.Unmarshal(body, &languages); err != nil {
+		return map[string]int{},
+			errors.New("error while deserializing repository languages: " + strconv.Itoa(statusCode) + shared.Separator + err.Error())
+	}
+	return languages, nil
+}
+
+// GetRepository returns the license detected by the instance, the releases older than Gitea 1.22 don't detect any
+func (g *Gitea) GetRepository(fullURL string) (*dto.RepositoryDetails, error) {
+	statusCode, body, err := g.httpRequest(fullURL)
+	if err != nil {
+		return &dto.RepositoryDetails{}, errors.New("error while getting repository: " + strconv.Itoa(statusCode) + shared.Separator + err.Error())
+	}
+	if statusCode == http.StatusNotFound {
+		log.Warnf("Repository not found for %s, skipping...", fullURL)
+		return &dto.RepositoryDetails{}, nil
+	}
+
+	var repository giteaRepository
+	if err = jsoniter.Unmarshal(body, &repository); err != nil {
+		return &dto.RepositoryDetails{},
+			errors.New("error while deserializing repository: " + strconv.Itoa(statusCode) + shared.Separator + err.Error())
+	}
+
+	return &dto.RepositoryDetails{
+		FullName: repository.FullName,
+		HTMLURL:  repository.HTMLURL,
+		License:  strings.Join(repository.Licenses, ","),
+	}, nil
+}
+
+func (g *Gitea) httpRequest(uri string) (statusCode int, body []byte, err error) {
+	if !g.guard.allows(uri) {
+		log.Errorf("Refusing to request %s, outside of the Gitea API %s", uri, g.URL)
+		return http.StatusInternalServerError, []byte{}, errors.New("refusing to request " + uri + ": outside of the Gitea API")
+	}
+
+	header := http.Header{}
+	if g.Token != "" {
+		header.Set(Authorization, GiteaTokenPrefix+g.Token)
+	}
+	resp, err := g.Transport.Do(&Request{Method: http.MethodGet, URL: uri, Header: header})
+	if err != nil {
+		return http.StatusInternalServerError, []byte{}, err
+	}
+	return resp.StatusCode, resp.Body, nil
+}
@@ -0,0 +1,152 @@
+package repositories
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"scalingo/internal/infra/config"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	s "github.com/stretchr/testify/suite"
+)
+
+type GiteaSuite struct {
+	s.Suite
+	server *httptest.Server
+	pages  []int
+	total  int
+	gitea  *Gitea
+}
+
+// giteaStub imitates the repository search and details endpoints of the Gitea API v1, the repositories with the IDs
+// 1 to total, 120 by default, are listed newest first, 50 per page
+func (suite *GiteaSuite) giteaStub(w http.ResponseWriter, r *http.Request) {
+	assert.Equal(suite.T(), "token secret", r.Header.Get(Authorization))
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/")
+	switch path {
+	case GiteaSearchEndpoint:
+		query := r.URL.Query()
+		assert.Equal(suite.T(), "created", query.Get("sort"))
+		assert.Equal(suite.T(), "desc", query.Get("order"))
+		page, _ := strconv.Atoi(query.Get("page"))
+		suite.pages = append(suite.pages, page)
+
+		data := make([]map[string]any, 0)
+		for id := suite.total - (page-1)*50; id > max(suite.total-page*50, 0); id-- {
+			data = append(data, suite.repository(id))
+		}
+		suite.write(w, map[string]any{"ok": true, "data": data})
+	case "repos/owner/repo_120/languages":
+		_, _ = w.Write([]byte(`{"Go":1200,"Makefile":34}`))
+	case "repos/owner/repo_120":
+		suite.write(w, suite.repository(120))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"The target couldn't be found."}`))
+	}
+}
+
+func (suite *GiteaSuite) repository(id int) map[string]any {
+	name := "repo_" + strconv.Itoa(id)
+	repository := map[string]any{
+		"id": id, "name": name, "full_name": "owner/" + name, "owner": map[string]any{"login": "owner"},
+		"html_url": "https://codeberg.org/owner/" + name, "description": "", "fork": id%10 == 0 && id != 120,
+	}
+	if id == 120 {
+		repository["licenses"] = []string{"MIT", "Apache-2.0"}
+	}
+	return repository
+}
+
+func (suite *GiteaSuite) write(w http.ResponseWriter, v any) {
+	body, err := jsoniter.Marshal(v)
+	assert.NoError(suite.T(), err)
+	_, _ = w.Write(body)
+}
+
+func (suite *GiteaSuite) SetupTest() {
+	suite.pages = nil
+	suite.total = 120
+	suite.server = httptest.NewServer(http.HandlerFunc(suite.giteaStub))
+	suite.gitea = newGitea(&config.Config{GitHubTransport: NetHTTPTransport}, "codeberg", suite.server.URL+"/api/v1", "secret")
+}
+
+func (suite *GiteaSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *GiteaSuite) TestGetLatestRepoID() {
+	id, err := suite.gitea.GetLatestRepoID()
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 70, id)
+}
+
+func (suite *GiteaSuite) TestGetRepositories_WalksBackToID() {
+	repos, _, err := suite.gitea.GetRepositories(60)
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []int{1, 2}, suite.pages)
+	// 61 to 120 without the forks 70, 80, 90, 100 and 110
+	assert.Equal(suite.T(), 55, len(repos))
+	assert.Equal(suite.T(), 61, repos[0].ID)
+	assert.Equal(suite.T(), 120, repos[len(repos)-1].ID)
+	assert.Equal(suite.T(), suite.server.URL+"/api/v1/repos/owner/repo_120/languages", repos[len(repos)-1].LanguagesURL)
+}
+
+// TestGetRepositories_BeyondMaxPages lists the 1234 repositories oldest page first, none being skipped past the bound
+func (suite *GiteaSuite) TestGetRepositories_BeyondMaxPages() {
+	suite.total = 1234
+
+	repos, next, err := suite.gitea.GetRepositories(0)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, repos[0].ID)
+	assert.Equal(suite.T(), 34, next) // the 26th page is the first empty one, the 25th holds 34 to 1
+
+	listed := map[int]bool{}
+	for since, calls := 0, 0; calls < 30; calls++ {
+		repos, next, err = suite.gitea.GetRepositories(since)
+		assert.NoError(suite.T(), err)
+		for _, repo := range repos {
+			listed[repo.ID] = true
+		}
+		if next == since {
+			break
+		}
+		since = next
+	}
+
+	for id := 1; id <= suite.total; id++ {
+		assert.Equal(suite.T(), id%10 != 0 || id == 120, listed[id], id)
+	}
+}
+
+func (suite *GiteaSuite) TestGetRepositoryLanguagesAndLicense() {
+	repos, _, err := suite.gitea.GetRepositories(119)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(repos))
+
+	languages, err := suite.gitea.GetRepositoryLanguages(repos[0].LanguagesURL)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), map[string]int{"Go": 1200, "Makefile": 34}, languages)
+
+	details, err := suite.gitea.GetRepository(repos[0].URL)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "MIT,Apache-2.0", details.License)
+	assert.Equal(suite.T(), "owner/repo_120", details.FullName)
+}
+
+func (suite *GiteaSuite) TestProvideSources_ByName() {
+	sources := ProvideSources(&config.Config{}, &Github{}, nil, []*Gitea{suite.gitea})
+
+	assert.Equal(suite.T(), suite.gitea, sources["codeberg"])
+}
+
+func TestGiteaSuite(t *testing.T) {
+	s.Run(t, new(GiteaSuite))
+}
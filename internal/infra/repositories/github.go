@@ -2,15 +2,19 @@ package repositories
 
 import (
 	"errors"
+	"io"
 	"net/http"
+	"net/url"
 	"scalingo/internal/core/dto"
+	"scalingo/internal/core/port"
 	conf "scalingo/internal/infra/config"
 	"scalingo/internal/shared"
+	"sort"
 	"strconv"
+	"sync"
 
 	jsoniter "github.com/json-iterator/go"
 	log "github.com/sirupsen/logrus"
-	"github.com/valyala/fasthttp"
 )
 
 const (
@@ -25,124 +29,160 @@ const (
 	RepositoryRefType = "repository"
 
 	Since = "?since="
+
+	LocationHeader = "Location"
 )
 
 type Github struct {
+	Name                   string
 	URL                    string
 	Token                  string
 	Version                string
 	LatestCreatedRepoRetry int
 	UseCredentials         bool
+	DetectVersion          bool
+	MaxRedirects           int
+	HeadStrategy           string
+	Transport              Transport
+
+	conditional *conditionalCache
+	credentials credentials
+	tokens      *tokenPool
+	detectOnce  sync.Once
+	guard       *urlGuard
+	head        knownHead
+	events      *EventsPoller
+	inflight    *shared.Coalescer
 }
 
 func ProvideGithub(config *conf.Config) *Github {
-	return &Github{
-		URL:                    config.GitHubURL,
-		Token:                  config.GitHubToken,
-		UseCredentials:         config.GitHubCredentials,
+	github := newGithub(config, port.DefaultSource, config.GitHubURL)
+	// The installation tokens are exchanged outside of the cassette, never recorded nor replayed
+	upstream := github.Transport
+	if config.GitHubCassette != "" {
+		transport, err := newCassetteTransport(config.GitHubCassetteMode, config.GitHubCassette, github.Transport)
+		if err != nil {
+			panic("Error initializing GitHub cassette: " + err.Error())
+		}
+		github.Transport = transport
+	}
+	switch {
+	case config.GitHubAppID != "":
+		credentials, err := newAppCredentials(
+			config.GitHubAppID,
+			config.GitHubAppInstallationID,
+			config.GitHubAppPrivateKeyFile,
+			github.endpoint,
+			config.GitHubVersion,
+			upstream,
+		)
+		if err != nil {
+			panic("Error initializing GitHub App credentials: " + err.Error())
+		}
+		github.credentials = credentials
+		github.UseCredentials = true
+	case config.GitHubCredentials:
+		github.Token = config.GitHubToken
+		github.UseCredentials = true
+		github.tokens = newTokenPool(append([]string{config.GitHubToken}, config.GitHubTokens...))
+		github.credentials = github.tokens
+	}
+	return github
+}
+
+// newGithub builds an unauthenticated adapter for the GitHub host, github.com or a GitHub Enterprise Server
+func newGithub(config *conf.Config, name, url string) *Github {
+	transport, err := newTransport(config)
+	if err != nil {
+		panic("Error initializing GitHub transport: " + err.Error())
+	}
+
+	github := &Github{
+		Name:                   name,
+		URL:                    normalizeBaseURL(url),
 		Version:                config.GitHubVersion,
 		LatestCreatedRepoRetry: config.LatestCreatedRepoRetry,
+		DetectVersion:          config.GitHubDetectVersion,
+		MaxRedirects:           config.GitHubMaxRedirects,
+		HeadStrategy:           config.GitHubHeadStrategy,
+		Transport:              transport,
+	}
+	github.guard = newURLGuard(github.URL)
+	github.inflight = shared.NewCoalescer("GitHub request")
+	if config.GitHubConditionalRequests {
+		github.conditional = newConditionalCache(config.GitHubConditionalCacheSize)
 	}
+	return github
 }
 
-type Repo struct {
-	ID int `json:"id"`
+// Close flushes the transport, the recorded cassette is only written then
+func (g *Github) Close() error {
+	if closer, ok := g.Transport.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
 }
-type Payload struct {
-	RefType string `json:"ref_type"`
+
+// TokenUsage reports the quota of every pooled token, identified by a fingerprint and never by its value
+func (g *Github) TokenUsage() []*dto.TokenUsage {
+	if g.tokens == nil {
+		return []*dto.TokenUsage{}
+	}
+	usage := g.tokens.usage()
+	for _, tokenUsage := range usage {
+		tokenUsage.Source = g.Name
+	}
+	return usage
 }
 
-type Event struct {
-	Type    string   `json:"type"`
-	Repo    *Repo    `json:"repo"`
-	Payload *Payload `json:"payload"`
+// SourcesTokenUsage reports the pooled tokens of every GitHub source, github.com along with the enterprise servers
+type SourcesTokenUsage struct {
+	sources port.Sources
 }
 
-func (g *Github) GetLatestRepoID() (int, error) {
-	for i := 0; i < g.LatestCreatedRepoRetry; i++ {
-		log.Warnf("try %d on %d to fetch latest created repo ID", i, g.LatestCreatedRepoRetry)
-		statusCode, b, err := httpRequest(&fasthttp.Client{}, g.Version, g.URL+EventsEndpoint, g.Token, g.UseCredentials)
-		if err != nil {
-			return 0, errors.New("error while getting latest repo id: " + strconv.Itoa(statusCode) + shared.Separator + err.Error())
-		}
+func ProvideTokenUsage(sources port.Sources) *SourcesTokenUsage {
+	return &SourcesTokenUsage{sources: sources}
+}
 
-		events := make([]*Event, 0)
-		err = jsoniter.Unmarshal(b, &events)
-		if err != nil {
-			return 0, errors.New("error while deserializing latest repo id: " + err.Error())
-		}
+// TokenUsage lists the tokens by source name
+func (s *SourcesTokenUsage) TokenUsage() []*dto.TokenUsage {
+	names := make([]string, 0, len(s.sources))
+	for name := range s.sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
-		for _, event := range events {
-			if event.Type == CreateEvent && event.Payload.RefType == RepositoryRefType {
-				log.Warnf("CreateEvent for %s detected...", event.Payload.RefType)
-				return event.Repo.ID, nil
-			}
+	usage := make([]*dto.TokenUsage, 0)
+	for _, name := range names {
+		if source, ok := s.sources[name].(port.TokenUsageInterface); ok {
+			usage = append(usage, source.TokenUsage()...)
 		}
 	}
-
-	return 0, errors.New("error while getting latest repo id: couldn't find latest ID")
-}
-
-type Owner struct {
-	Login string `json:"login"`
-}
-
-type Repository struct {
-	ID           int    `json:"id"`
-	Name         string `json:"name"`
-	FullName     string `json:"full_name"`
-	Owner        *Owner `json:"owner"`
-	LanguagesURL string `json:"languages_url"`
-	HTMLURL      string `json:"html_url"`
-	URL          string `json:"url"`
-	Description  any    `json:"description"`
-	Fork         bool   `json:"fork"`
+	return usage
 }
 
-func (g *Github) GetRepositories(id int) ([]*dto.LatestCreatedRepo, error) {
-	url := g.URL + RepoListEndpoint + Since + strconv.Itoa(id)
-	statusCode, repoList, err := httpRequest(&fasthttp.Client{}, g.Version, url, g.Token, g.UseCredentials)
+func (g *Github) GetRepositories(id int) ([]*dto.LatestCreatedRepo, int, error) {
+	url := g.endpoint(RepoListEndpoint) + Since + strconv.Itoa(id)
+	statusCode, repoList, err := g.httpRequest(url)
 	if err != nil {
-		return nil, errors.New("error while getting repositories: " + strconv.Itoa(statusCode) + shared.Separator + err.Error())
+		return nil, id, errors.New("error while getting repositories: " + strconv.Itoa(statusCode) + shared.Separator + err.Error())
 	}
 
-	repositories := make([]*Repository, 0)
-	err = jsoniter.Unmarshal(repoList, &repositories)
+	// Forks are excluded while decoding
+	latestCreatedRepos, next, err := decodeRepositories(repoList)
 	if err != nil {
-		return nil, errors.New("error while deserializing repositories: " + strconv.Itoa(statusCode) + shared.Separator + err.Error())
-	}
-
-	latestCreatedRepos := make([]*dto.LatestCreatedRepo, 0)
-
-	for _, repository := range repositories {
-		var assertedDesc string
-		if repository.Description != nil {
-			assertedDesc = repository.Description.(string)
-		}
-
-		if !repository.Fork { // excluding forks
-			latestCreatedRepos = append(latestCreatedRepos, &dto.LatestCreatedRepo{
-				ID:           repository.ID,
-				Name:         repository.Name,
-				FullName:     repository.FullName,
-				Owner:        &dto.Owner{Login: repository.Owner.Login},
-				URL:          repository.URL,
-				LanguagesURL: repository.LanguagesURL,
-				HTMLURL:      repository.HTMLURL,
-				Description:  assertedDesc,
-			})
-		}
+		return nil, id, errors.New("error while deserializing repositories: " + strconv.Itoa(statusCode) + shared.Separator + err.Error())
 	}
-	return latestCreatedRepos, nil
+	return latestCreatedRepos, max(next, id), nil
 }
 
 func (g *Github) GetRepositoryLanguages(fullURL string) (map[string]int, error) {
-	statusCode, repoList, err := httpRequest(&fasthttp.Client{}, g.Version, fullURL, g.Token, g.UseCredentials)
+	statusCode, repoList, err := g.httpRequestFollowing(fullURL)
 	if err != nil {
 		return map[string]int{},
 			errors.New("error while getting repository languages: " + strconv.Itoa(statusCode) + shared.Separator + err.Error())
 	}
-	if statusCode == http.StatusNotFound || statusCode == http.StatusMovedPermanently {
+	if statusCode == http.StatusNotFound || isRedirect(statusCode) {
 		log.Warnf("Language not found for %s, skipping...", fullURL)
 		return map[string]int{}, nil
 	}
@@ -156,51 +196,172 @@ func (g *Github) GetRepositoryLanguages(fullURL string) (map[string]int, error)
 	return languages, nil
 }
 
-type spdx struct {
-	License map[string]string `json:"license"`
+type repositoryDetails struct {
+	FullName string            `json:"full_name"`
+	HTMLURL  string            `json:"html_url"`
+	License  map[string]string `json:"license"`
 }
 
-func (g *Github) GetRepositorySPDX(fullURL string) (string, error) {
-	statusCode, repoList, err := httpRequest(&fasthttp.Client{}, g.Version, fullURL, g.Token, g.UseCredentials)
+func (g *Github) GetRepository(fullURL string) (*dto.RepositoryDetails, error) {
+	statusCode, repo, err := g.httpRequestFollowing(fullURL)
 	if err != nil {
-		return "", errors.New("error while getting repository SPDX: " + strconv.Itoa(statusCode) + shared.Separator + err.Error())
+		return &dto.RepositoryDetails{}, errors.New("error while getting repository: " + strconv.Itoa(statusCode) + shared.Separator + err.Error())
 	}
-	if statusCode == http.StatusNotFound || statusCode == http.StatusMovedPermanently {
-		log.Warnf("License not found for %s, skipping...", fullURL)
-		return "", nil
+	if statusCode == http.StatusNotFound || isRedirect(statusCode) {
+		log.Warnf("Repository not found for %s, skipping...", fullURL)
+		return &dto.RepositoryDetails{}, nil
 	}
 
-	var s spdx
-	err = jsoniter.Unmarshal(repoList, &s)
+	var details repositoryDetails
+	err = jsoniter.Unmarshal(repo, &details)
 	if err != nil {
-		return "", errors.New("error while deserializing repository SPDX: " + strconv.Itoa(statusCode) + shared.Separator + err.Error())
+		return &dto.RepositoryDetails{},
+			errors.New("error while deserializing repository: " + strconv.Itoa(statusCode) + shared.Separator + err.Error())
 	}
 
-	if id, ok := s.License["spdx_id"]; ok {
-		return id, nil
-	}
+	// The full name is the current one, it differs from the listed one when the repository has been renamed or transferred
+	return &dto.RepositoryDetails{
+		FullName: details.FullName,
+		HTMLURL:  details.HTMLURL,
+		License:  details.License["spdx_id"],
+	}, nil
+}
 
-	return "", nil
+// httpRequestFollowing follows the redirections GitHub answers for renamed or transferred repositories, up to MaxRedirects hops
+func (g *Github) httpRequestFollowing(uri string) (statusCode int, body []byte, err error) {
+	for hop := 0; ; hop++ {
+		resp, err := g.send(http.MethodGet, uri, nil)
+		if err != nil {
+			return http.StatusInternalServerError, []byte{}, err
+		}
+
+		location := resp.Header.Get(LocationHeader)
+		if !isRedirect(resp.StatusCode) || location == "" || hop >= g.MaxRedirects {
+			return resp.StatusCode, resp.Body, nil
+		}
+
+		current, err := url.Parse(uri)
+		if err != nil {
+			return http.StatusInternalServerError, []byte{}, err
+		}
+		next, err := current.Parse(location)
+		if err != nil {
+			return resp.StatusCode, []byte{}, errors.New("invalid redirection to " + location + shared.Separator + err.Error())
+		}
+		log.Infof("%s moved to %s, following...", uri, next.String())
+		uri = next.String()
+	}
 }
 
-func httpRequest(client *fasthttp.Client, version, uri, token string, useCred bool) (statusCode int, body []byte, err error) {
-	req := fasthttp.AcquireRequest()
-	req.SetRequestURI(uri)
-	req.Header.SetMethod(http.MethodGet)
-	req.Header.Set(GithubVersionHeader, version)
-	if useCred {
-		req.Header.Set(Authorization, Bearer+token)
+func isRedirect(statusCode int) bool {
+	switch statusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
 	}
-	resp := fasthttp.AcquireResponse()
-	err = client.Do(req, resp)
+	return false
+}
+
+func (g *Github) httpRequest(uri string) (statusCode int, body []byte, err error) {
+	resp, err := g.send(http.MethodGet, uri, nil)
 	if err != nil {
 		return http.StatusInternalServerError, []byte{}, err
 	}
-	fasthttp.ReleaseRequest(req)
+	return resp.StatusCode, resp.Body, nil
+}
+
+// send shares the response of an in-flight GET of the same URL with the concurrent callers
+func (g *Github) send(method, uri string, payload []byte) (*Response, error) {
+	if g.inflight == nil || method != http.MethodGet || payload != nil {
+		return g.do(method, uri, payload)
+	}
+
+	resp, err := g.inflight.Do(uri, func() (any, error) {
+		return g.do(method, uri, payload)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*Response), nil
+}
+
+func (g *Github) do(method, uri string, payload []byte) (*Response, error) {
+	g.detect()
+	if !g.allows(uri) {
+		log.Errorf("Refusing to request %s, outside of the GitHub API %s", uri, g.URL)
+		return nil, errors.New("refusing to request " + uri + ": outside of the GitHub API")
+	}
+
+	header := http.Header{}
+	if g.Version != "" {
+		header.Set(GithubVersionHeader, g.Version)
+	}
+	var token string
+	if g.UseCredentials {
+		var err error
+		if token, err = g.bearer(); err != nil {
+			return nil, err
+		}
+		header.Set(Authorization, Bearer+token)
+	}
+	conditional := g.conditional != nil && method == http.MethodGet
+	if conditional {
+		g.conditional.prepare(uri, header)
+	}
+
+	resp, err := g.roundTrip(&Request{Method: method, URL: uri, Header: header, Body: payload}, token)
+	if err != nil || !conditional {
+		return resp, err
+	}
+
+	resp, resolved := g.conditional.resolve(uri, resp)
+	if !resolved {
+		// The cached body was evicted since the validators were sent, the request is sent again unconditionally
+		log.Infof("%s not modified but no longer cached, requesting it again", uri)
+		unconditional := header.Clone()
+		unconditional.Del(IfNoneMatchHeader)
+		unconditional.Del(IfModifiedSinceHeader)
+		if resp, err = g.roundTrip(&Request{Method: method, URL: uri, Header: unconditional, Body: payload}, token); err != nil {
+			return nil, err
+		}
+		resp, _ = g.conditional.resolve(uri, resp)
+	}
+	return resp, nil
+}
+
+func (g *Github) roundTrip(req *Request, token string) (*Response, error) {
+	resp, err := g.Transport.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if g.credentials != nil && token != "" {
+		g.credentials.observe(token, resp)
+	}
+	return resp, nil
+}
+
+func (g *Github) bearer() (string, error) {
+	if g.credentials == nil {
+		return g.Token, nil
+	}
+	return g.credentials.token()
+}
 
-	body = resp.Body()
-	statusCode = resp.StatusCode()
+// endpoint resolves a path relative to the API root, e.g. https://api.github.com/ or https://host/api/v3/,
+// once the server is detected since the root of a host-only enterprise URL moves under /api/v3/
+func (g *Github) endpoint(path string) string {
+	g.detect()
+	return g.URL + path
+}
 
-	fasthttp.ReleaseResponse(resp)
-	return statusCode, body, nil
+func (g *Github) detect() {
+	g.detectOnce.Do(g.detectServer)
+}
+
+// allows checks the URL against the allowlist derived from the API root, the URLs from upstream answers
+// (languages_url, url, Location) are never trusted to carry the credentials elsewhere
+func (g *Github) allows(uri string) bool {
+	if g.guard == nil {
+		return newURLGuard(g.endpoint("")).allows(uri)
+	}
+	return g.guard.allows(uri)
 }
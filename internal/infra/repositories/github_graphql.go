@@ -0,0 +1,233 @@
+package repositories
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"scalingo/internal/core/dto"
+	"scalingo/internal/core/port"
+	conf "scalingo/internal/infra/config"
+	"scalingo/internal/shared"
+
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	RESTAPI    = "rest"
+	GraphQLAPI = "graphql"
+
+	GraphQLEndpoint = "graphql"
+	ReposPath       = "repos/"
+	LanguagesPath   = "/languages"
+
+	// GitHub refuses more than 100 node IDs per query
+	graphQLMaxNodes = 100
+)
+
+const repositoryFields = `
+	databaseId
+	nameWithOwner
+	url
+	licenseInfo { spdxId }
+	languages(first: 100) { edges { size node { name } } }
+`
+
+const nodesQuery = `query($ids: [ID!]!) { nodes(ids: $ids) { ... on Repository {` + repositoryFields + `} } }`
+
+const repositoryQuery = `query($owner: String!, $name: String!) { repository(owner: $owner, name: $name) {` + repositoryFields + `} }`
+
+// ProvideGithubAdapter selects the GitHub API used to enrich repositories, REST by default
+func ProvideGithubAdapter(config *conf.Config, github *Github, offline *Offline) port.GithubInterface {
+	if offline != nil {
+		return offline
+	}
+	return newGithubAdapter(config.GitHubAPI, config.GitHubGraphQLURL, github)
+}
+
+func newGithubAdapter(api, graphQLURL string, github *Github) port.GithubInterface {
+	switch api {
+	case "", RESTAPI:
+		return github
+	case GraphQLAPI:
+		return NewGithubGraphQL(github, graphQLURL)
+	default:
+		panic("Error initializing GitHub adapter: unknown API " + api)
+	}
+}
+
+// GithubGraphQL relies on the REST API for the events and the repository listing
+// but enriches the repositories through the GraphQL v4 API, a whole page in a single round trip
+type GithubGraphQL struct {
+	*Github
+	GraphQLURL string // derived from the REST API root when empty
+}
+
+func NewGithubGraphQL(github *Github, graphQLURL string) *GithubGraphQL {
+	if github.guard == nil {
+		github.guard = newURLGuard(github.URL)
+	}
+	// On GitHub Enterprise Server the GraphQL endpoint is outside of the REST API root
+	if graphQLURL == "" {
+		github.guard.allow(deriveGraphQLURL(github.URL))
+	} else {
+		github.guard.allow(graphQLURL)
+	}
+	return &GithubGraphQL{Github: github, GraphQLURL: graphQLURL}
+}
+
+// graphQLURL derives the endpoint once the server is detected, the root of a host-only enterprise URL having moved
+func (g *GithubGraphQL) graphQLURL() string {
+	if g.GraphQLURL != "" {
+		return g.GraphQLURL
+	}
+	return deriveGraphQLURL(g.endpoint(""))
+}
+
+type graphQLRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+}
+
+type graphQLError struct {
+	Message string `json:"message"`
+}
+
+type graphQLLanguageEdge struct {
+	Size int `json:"size"`
+	Node struct {
+		Name string `json:"name"`
+	} `json:"node"`
+}
+
+type graphQLRepository struct {
+	DatabaseID    int    `json:"databaseId"`
+	NameWithOwner string `json:"nameWithOwner"`
+	URL           string `json:"url"`
+	LicenseInfo   *struct {
+		SpdxID string `json:"spdxId"`
+	} `json:"licenseInfo"`
+	Languages struct {
+		Edges []*graphQLLanguageEdge `json:"edges"`
+	} `json:"languages"`
+}
+
+func (r *graphQLRepository) enrichment() *dto.RepositoryEnrichment {
+	enrichment := &dto.RepositoryEnrichment{
+		FullName:  r.NameWithOwner,
+		HTMLURL:   r.URL,
+		Languages: make(map[string]int, len(r.Languages.Edges)),
+	}
+	if r.LicenseInfo != nil {
+		enrichment.License = r.LicenseInfo.SpdxID
+	}
+	for _, edge := range r.Languages.Edges {
+		enrichment.Languages[edge.Node.Name] = edge.Size
+	}
+	return enrichment
+}
+
+type graphQLNodesResponse struct {
+	Data struct {
+		Nodes []*graphQLRepository `json:"nodes"`
+	} `json:"data"`
+	Errors []*graphQLError `json:"errors"`
+}
+
+type graphQLRepositoryResponse struct {
+	Data struct {
+		Repository *graphQLRepository `json:"repository"`
+	} `json:"data"`
+	Errors []*graphQLError `json:"errors"`
+}
+
+func (g *GithubGraphQL) EnrichRepositories(repos []*dto.LatestCreatedRepo) (map[int]*dto.RepositoryEnrichment, error) {
+	enrichments := make(map[int]*dto.RepositoryEnrichment, len(repos))
+
+	ids := make([]string, 0, len(repos))
+	for _, repo := range repos {
+		if repo.NodeID != "" {
+			ids = append(ids, repo.NodeID)
+		}
+	}
+
+	for start := 0; start < len(ids); start += graphQLMaxNodes {
+		end := min(start+graphQLMaxNodes, len(ids))
+
+		var resp graphQLNodesResponse
+		if err := g.query(nodesQuery, map[string]any{"ids": ids[start:end]}, &resp); err != nil {
+			return nil, errors.New("error while enriching repositories: " + err.Error())
+		}
+		// Partial errors (e.g. a repository deleted since the listing) come with the resolvable nodes
+		for _, gqlErr := range resp.Errors {
+			log.Warnf("GraphQL enrichment partial error: %s", gqlErr.Message)
+		}
+
+		for _, node := range resp.Data.Nodes {
+			if node != nil && node.DatabaseID != 0 {
+				enrichments[node.DatabaseID] = node.enrichment()
+			}
+		}
+	}
+
+	return enrichments, nil
+}
+
+func (g *GithubGraphQL) GetRepositoryLanguages(fullURL string) (map[string]int, error) {
+	repo, err := g.repository(strings.TrimSuffix(fullURL, LanguagesPath))
+	if err != nil {
+		return map[string]int{}, errors.New("error while getting repository languages: " + err.Error())
+	}
+	if repo == nil {
+		log.Warnf("Language not found for %s, skipping...", fullURL)
+		return map[string]int{}, nil
+	}
+	return repo.enrichment().Languages, nil
+}
+
+func (g *GithubGraphQL) GetRepository(fullURL string) (*dto.RepositoryDetails, error) {
+	repo, err := g.repository(fullURL)
+	if err != nil {
+		return &dto.RepositoryDetails{}, errors.New("error while getting repository: " + err.Error())
+	}
+	if repo == nil {
+		log.Warnf("Repository not found for %s, skipping...", fullURL)
+		return &dto.RepositoryDetails{}, nil
+	}
+
+	enrichment := repo.enrichment()
+	return &dto.RepositoryDetails{FullName: enrichment.FullName, HTMLURL: enrichment.HTMLURL, License: enrichment.License}, nil
+}
+
+// repository resolves a REST repository URL (https://api.github.com/repos/{owner}/{name}) through GraphQL
+func (g *GithubGraphQL) repository(fullURL string) (*graphQLRepository, error) {
+	owner, name, ok := strings.Cut(strings.TrimPrefix(fullURL, g.endpoint(ReposPath)), "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return nil, errors.New("unexpected repository URL " + fullURL)
+	}
+
+	var resp graphQLRepositoryResponse
+	if err := g.query(repositoryQuery, map[string]any{"owner": owner, "name": name}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Data.Repository, nil
+}
+
+func (g *GithubGraphQL) query(query string, variables map[string]any, out any) error {
+	payload, err := jsoniter.Marshal(&graphQLRequest{Query: query, Variables: variables})
+	if err != nil {
+		return err
+	}
+
+	resp, err := g.send(http.MethodPost, g.graphQLURL(), payload)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(strconv.Itoa(resp.StatusCode) + shared.Separator + string(resp.Body))
+	}
+
+	return jsoniter.Unmarshal(resp.Body, out)
+}
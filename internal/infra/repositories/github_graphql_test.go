@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"scalingo/internal/core/dto"
+	"scalingo/internal/infra/config"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	s "github.com/stretchr/testify/suite"
+)
+
+type GithubGraphQLSuite struct {
+	s.Suite
+	server  *httptest.Server
+	queries []*graphQLRequest
+	github  *GithubGraphQL
+}
+
+// graphQLStub imitates the GitHub GraphQL endpoint for the nodes and repository queries
+func (suite *GithubGraphQLSuite) graphQLStub(w http.ResponseWriter, r *http.Request) {
+	assert.Equal(suite.T(), http.MethodPost, r.Method)
+	assert.Equal(suite.T(), "Bearer secret", r.Header.Get(Authorization))
+
+	body, err := io.ReadAll(r.Body)
+	assert.NoError(suite.T(), err)
+	query := &graphQLRequest{}
+	assert.NoError(suite.T(), jsoniter.Unmarshal(body, query))
+	suite.queries = append(suite.queries, query)
+
+	repoOne := `{"databaseId":1,"nameWithOwner":"john_doe/repo_one","licenseInfo":{"spdxId":"MIT"},
+		"languages":{"edges":[{"size":10,"node":{"name":"Go"}},{"size":50,"node":{"name":"Java"}}]}}`
+	repoTwo := `{"databaseId":2,"nameWithOwner":"jane_doe/repo_two","licenseInfo":null,"languages":{"edges":[]}}`
+
+	switch {
+	case query.Variables["ids"] != nil:
+		_, _ = w.Write([]byte(`{"data":{"nodes":[` + repoOne + `,` + repoTwo + `,null]},
+			"errors":[{"message":"Could not resolve to a node with the global id of 'gone'"}]}`))
+	case query.Variables["name"] == "repo_one":
+		_, _ = w.Write([]byte(`{"data":{"repository":` + repoOne + `}}`))
+	default:
+		_, _ = w.Write([]byte(`{"data":{"repository":null},"errors":[{"type":"NOT_FOUND","message":"Could not resolve"}]}`))
+	}
+}
+
+func (suite *GithubGraphQLSuite) SetupTest() {
+	suite.queries = nil
+	suite.server = httptest.NewServer(http.HandlerFunc(suite.graphQLStub))
+
+	transport, err := newTransport(&config.Config{GitHubTransport: NetHTTPTransport})
+	assert.NoError(suite.T(), err)
+
+	suite.github = NewGithubGraphQL(&Github{
+		URL:            suite.server.URL + "/",
+		Token:          "secret",
+		UseCredentials: true,
+		Transport:      transport,
+	}, "")
+}
+
+func (suite *GithubGraphQLSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *GithubGraphQLSuite) TestEnrichRepositories() {
+	enrichments, err := suite.github.EnrichRepositories([]*dto.LatestCreatedRepo{
+		{ID: 1, NodeID: "R_one"},
+		{ID: 2, NodeID: "R_two"},
+		{ID: 3, NodeID: "gone"},
+	})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(suite.queries))
+	assert.Equal(suite.T(), []any{"R_one", "R_two", "gone"}, suite.queries[0].Variables["ids"])
+	assert.Equal(suite.T(), 2, len(enrichments))
+	assert.Equal(suite.T(), "MIT", enrichments[1].License)
+	assert.Equal(suite.T(), map[string]int{"Go": 10, "Java": 50}, enrichments[1].Languages)
+	assert.Equal(suite.T(), "", enrichments[2].License)
+}
+
+func (suite *GithubGraphQLSuite) TestGetRepository() {
+	details, err := suite.github.GetRepository(suite.server.URL + "/repos/john_doe/repo_one")
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "MIT", details.License)
+	assert.Equal(suite.T(), "john_doe/repo_one", details.FullName)
+	assert.Equal(suite.T(), "john_doe", suite.queries[0].Variables["owner"])
+}
+
+func (suite *GithubGraphQLSuite) TestGetRepositoryLanguages_NotFound() {
+	languages, err := suite.github.GetRepositoryLanguages(suite.server.URL + "/repos/jane_doe/unknown/languages")
+
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), languages)
+}
+
+func TestGithubGraphQLSuite(t *testing.T) {
+	s.Run(t, new(GithubGraphQLSuite))
+}
@@ -0,0 +1,523 @@
+package repositories
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"scalingo/internal/infra/config"
+	"scalingo/internal/shared"
+
+	"github.com/stretchr/testify/assert"
+	s "github.com/stretchr/testify/suite"
+)
+
+type GithubSuite struct {
+	s.Suite
+	transport *fakeTransport
+	github    *Github
+}
+
+// fakeTransport answers with canned responses indexed by URL, or with the handler, and records the received requests
+type fakeTransport struct {
+	sync.Mutex
+	responses map[string]*Response
+	handler   func(req *Request) *Response
+	requests  []*Request
+}
+
+func (f *fakeTransport) Do(req *Request) (*Response, error) {
+	f.Lock()
+	f.requests = append(f.requests, req)
+	f.Unlock()
+	if resp, ok := f.responses[req.URL]; ok {
+		return resp, nil
+	}
+	if f.handler != nil {
+		return f.handler(req), nil
+	}
+	return &Response{StatusCode: http.StatusNotFound, Header: http.Header{}, Body: []byte(`{"message":"Not Found"}`)}, nil
+}
+
+func (suite *GithubSuite) SetupTest() {
+	suite.transport = &fakeTransport{responses: map[string]*Response{}}
+	suite.github = &Github{
+		URL:                    "https://api.github.com/",
+		Token:                  "secret",
+		Version:                "2022-11-28",
+		LatestCreatedRepoRetry: 1,
+		UseCredentials:         true,
+		Transport:              suite.transport,
+	}
+}
+
+func (suite *GithubSuite) TearDownTest() {}
+
+func (suite *GithubSuite) TestGetRepositories_ExcludesForks() {
+	suite.transport.responses["https://api.github.com/repositories?since=10"] = &Response{
+		StatusCode: http.StatusOK,
+		Body: []byte(`[
+			{"id":11,"name":"one","full_name":"john_doe/one","owner":{"login":"john_doe"},"description":null,"fork":false},
+			{"id":12,"name":"two","full_name":"jane_doe/two","owner":{"login":"jane_doe"},"description":"forked","fork":true}
+		]`),
+	}
+
+	repos, next, err := suite.github.GetRepositories(10)
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(repos))
+	assert.Equal(suite.T(), 12, next)
+	assert.Equal(suite.T(), "john_doe/one", repos[0].FullName)
+	assert.Equal(suite.T(), "", repos[0].Description)
+	assert.Equal(suite.T(), "Bearer secret", suite.transport.requests[0].Header.Get(Authorization))
+	assert.Equal(suite.T(), "2022-11-28", suite.transport.requests[0].Header.Get(GithubVersionHeader))
+}
+
+func (suite *GithubSuite) TestEnterpriseServer_LegacyVersion() {
+	github := newGithub(&config.Config{GitHubVersion: "2022-11-28", GitHubDetectVersion: true}, "corp", "https://ghe.corp/api/v3")
+	github.Transport = suite.transport
+	suite.transport.responses["https://ghe.corp/api/v3/meta"] = &Response{
+		StatusCode: http.StatusOK,
+		Body:       []byte(`{"installed_version":"3.8.12","verifiable_password_authentication":true}`),
+	}
+	suite.transport.responses["https://ghe.corp/api/v3/repositories?since=10"] = &Response{StatusCode: http.StatusOK, Body: []byte(`[]`)}
+
+	_, _, err := github.GetRepositories(10)
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, len(suite.transport.requests))
+	assert.Equal(suite.T(), "https://ghe.corp/api/v3/repositories?since=10", suite.transport.requests[1].URL)
+	assert.Empty(suite.T(), suite.transport.requests[1].Header.Get(GithubVersionHeader))
+	assert.Equal(suite.T(), "https://ghe.corp/api/graphql", deriveGraphQLURL(github.URL))
+}
+
+// TestEnterpriseServer_HostOnly moves a host-only URL to the /api/v3/ root answering a GitHub Enterprise Server /meta,
+// the events feed, the listing and the GraphQL endpoint all being requested under it
+func (suite *GithubSuite) TestEnterpriseServer_HostOnly() {
+	github := newGithub(&config.Config{GitHubVersion: "2022-11-28", GitHubDetectVersion: true}, "corp", "https://ghe.corp")
+	github.Transport = suite.transport
+	suite.transport.responses["https://ghe.corp/api/v3/meta"] = &Response{
+		StatusCode: http.StatusOK,
+		Body:       []byte(`{"installed_version":"3.12.1","verifiable_password_authentication":true}`),
+	}
+	suite.transport.responses["https://ghe.corp/api/v3/events?per_page=100"] = &Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body: []byte(`[{"id":"3","type":"CreateEvent","actor":{"login":"john_doe"},"repo":{"id":42,"name":"john_doe/new"},
+			"payload":{"ref":null,"ref_type":"repository"},"created_at":"2024-03-01T10:03:00Z"}]`),
+	}
+	suite.transport.responses["https://ghe.corp/api/v3/repositories?since=42"] = &Response{StatusCode: http.StatusOK, Body: []byte(`[]`)}
+
+	poller := NewEventsPoller(github, time.Minute, 1, 10)
+	_, err := poller.Poll()
+	assert.NoError(suite.T(), err)
+	id, ok := poller.LatestCreatedRepoID()
+	assert.True(suite.T(), ok)
+	_, _, err = github.GetRepositories(id)
+	assert.NoError(suite.T(), err)
+
+	urls := make([]string, 0, len(suite.transport.requests))
+	for _, req := range suite.transport.requests {
+		urls = append(urls, req.URL)
+	}
+	assert.Equal(suite.T(), []string{
+		"https://ghe.corp/meta",
+		"https://ghe.corp/api/v3/meta",
+		"https://ghe.corp/api/v3/events?per_page=100",
+		"https://ghe.corp/api/v3/repositories?since=42",
+	}, urls)
+	assert.Equal(suite.T(), "2022-11-28", suite.transport.requests[3].Header.Get(GithubVersionHeader))
+	assert.Equal(suite.T(), "https://ghe.corp/api/graphql", NewGithubGraphQL(github, "").graphQLURL())
+	assert.True(suite.T(), github.allows("https://ghe.corp/api/graphql"))
+}
+
+// TestEnterpriseServer_PrivateMode authenticates the /meta probe and reports the enterprise tokens along with the others
+func (suite *GithubSuite) TestEnterpriseServer_PrivateMode() {
+	sources := ProvideSources(&config.Config{
+		GitHubDetectVersion:    true,
+		GitHubEnterpriseURLs:   map[string]string{"corp": "https://ghe.corp/api/v3"},
+		GitHubEnterpriseTokens: map[string]string{"corp": "enterprise-secret"},
+	}, suite.github, nil, nil)
+	enterprise := sources["corp"].(*Github)
+	enterprise.Transport = suite.transport
+	suite.transport.responses["https://ghe.corp/api/v3/meta"] = &Response{
+		StatusCode: http.StatusOK,
+		Body:       []byte(`{"installed_version":"3.12.1"}`),
+	}
+
+	assert.Equal(suite.T(), "https://ghe.corp/api/v3/repos/", enterprise.endpoint(ReposPath))
+	assert.Equal(suite.T(), "Bearer enterprise-secret", suite.transport.requests[0].Header.Get(Authorization))
+
+	usage := ProvideTokenUsage(sources).TokenUsage()
+	assert.Equal(suite.T(), 1, len(usage))
+	assert.Equal(suite.T(), "corp", usage[0].Source)
+}
+
+// TestEnterpriseServer_HostOnlyProxy keeps the root of a host-only URL answering a github.com /meta, e.g. a proxy
+func (suite *GithubSuite) TestEnterpriseServer_HostOnlyProxy() {
+	github := newGithub(&config.Config{GitHubDetectVersion: true}, "proxy", "http://127.0.0.1:8080/")
+	github.Transport = suite.transport
+	suite.transport.responses["http://127.0.0.1:8080/meta"] = &Response{StatusCode: http.StatusOK, Body: []byte(`{"verifiable_password_authentication":true}`)}
+
+	assert.Equal(suite.T(), "http://127.0.0.1:8080/repositories", github.endpoint(RepoListEndpoint))
+	assert.Equal(suite.T(), 1, len(suite.transport.requests))
+}
+
+func (suite *GithubSuite) TestGetLatestRepoID_SearchFallback() {
+	suite.github.HeadStrategy = SearchHeadStrategy
+	suite.transport.handler = func(*Request) *Response {
+		return &Response{StatusCode: http.StatusInternalServerError, Header: http.Header{}, Body: []byte(`{"message":"Server Error"}`)}
+	}
+	suite.transport.responses["https://api.github.com/events"] = &Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       readTestdata(suite.T(), "events.json"),
+	}
+
+	id, err := suite.github.GetLatestRepoID()
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 755000150, id)
+}
+
+func (suite *GithubSuite) TestEventsPoller() {
+	suite.github.conditional = newConditionalCache(10)
+	first := http.Header{}
+	first.Set(PollIntervalHeader, "120")
+	first.Set(ETagHeader, `"v1"`)
+	first.Set(LinkHeader, `<https://api.github.com/events?per_page=100&page=2>; rel="next", <https://api.github.com/events?per_page=100&page=3>; rel="last"`)
+	suite.transport.responses["https://api.github.com/events?per_page=100"] = &Response{
+		StatusCode: http.StatusOK,
+		Header:     first,
+		Body: []byte(`[{"id":"4","type":"PushEvent","actor":{"login":"jane_doe"},"repo":{"id":2,"name":"jane_doe/two"},
+			"payload":{"ref":"refs/heads/main","commits":[{"sha":"abc"}]},"created_at":"2024-03-01T10:04:00Z"},
+			{"id":"3","type":"CreateEvent","actor":{"login":"john_doe"},"repo":{"id":42,"name":"john_doe/new"},
+			"payload":{"ref":null,"ref_type":"repository"},"created_at":"2024-03-01T10:03:00Z"}]`),
+	}
+	suite.transport.responses["https://api.github.com/events?per_page=100&page=2"] = &Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body: []byte(`[{"id":"2","type":"WatchEvent","actor":{"login":"bob"},"repo":{"id":1,"name":"john_doe/one"},
+			"payload":{"action":"started"},"created_at":"2024-03-01T10:02:00Z"}]`),
+	}
+
+	poller := NewEventsPoller(suite.github, time.Minute, 3, 2)
+	events, unsubscribe := poller.Subscribe(10)
+	defer unsubscribe()
+
+	wait, err := poller.Poll()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2*time.Minute, wait)
+
+	// The buffer keeps the two most recent events, in chronological order
+	recent := poller.Recent()
+	assert.Equal(suite.T(), 2, len(recent))
+	assert.Equal(suite.T(), "3", recent[0].ID)
+	assert.Equal(suite.T(), "4", recent[1].ID)
+	assert.Equal(suite.T(), "refs/heads/main", recent[1].Ref)
+	assert.Equal(suite.T(), "2", (<-events).ID)
+	id, ok := poller.LatestCreatedRepoID()
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), 42, id)
+
+	// Nothing new, GitHub answers 304 to the revalidation
+	suite.transport.responses["https://api.github.com/events?per_page=100"] = &Response{StatusCode: http.StatusNotModified, Header: first}
+	suite.transport.requests = nil
+	_, err = poller.Poll()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(suite.transport.requests))
+	assert.Equal(suite.T(), `"v1"`, suite.transport.requests[0].Header.Get(IfNoneMatchHeader))
+	assert.Equal(suite.T(), 2, len(poller.Recent()))
+
+	suite.github.events = poller
+	latest, err := suite.github.GetLatestRepoID()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 42, latest)
+}
+
+func (suite *GithubSuite) TestGetRepositoryLanguages_NotFound() {
+	languages, err := suite.github.GetRepositoryLanguages("https://api.github.com/repos/john_doe/one/languages")
+
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), languages)
+}
+
+func (suite *GithubSuite) TestGetRepository_FollowsRenames() {
+	suite.github.MaxRedirects = 2
+	moved := http.Header{}
+	moved.Set(LocationHeader, "https://api.github.com/repositories/11")
+	suite.transport.responses["https://api.github.com/repos/john_doe/one"] = &Response{StatusCode: http.StatusMovedPermanently, Header: moved}
+	suite.transport.responses["https://api.github.com/repositories/11"] = &Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       []byte(`{"full_name":"jane_doe/uno","html_url":"https://github.com/jane_doe/uno","license":{"spdx_id":"MIT"}}`),
+	}
+
+	details, err := suite.github.GetRepository("https://api.github.com/repos/john_doe/one")
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "jane_doe/uno", details.FullName)
+	assert.Equal(suite.T(), "MIT", details.License)
+}
+
+func (suite *GithubSuite) TestGetRepository_RedirectLoop() {
+	suite.github.MaxRedirects = 2
+	loop := http.Header{}
+	loop.Set(LocationHeader, "/repos/john_doe/one")
+	suite.transport.responses["https://api.github.com/repos/john_doe/one"] = &Response{StatusCode: http.StatusMovedPermanently, Header: loop}
+
+	details, err := suite.github.GetRepository("https://api.github.com/repos/john_doe/one")
+
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), details.FullName)
+	assert.Equal(suite.T(), 3, len(suite.transport.requests))
+}
+
+func (suite *GithubSuite) TestGuard_RejectsForeignURLs() {
+	suite.github.MaxRedirects = 2
+	foreign := http.Header{}
+	foreign.Set(LocationHeader, "https://evil.example.com/repos/john_doe/one")
+	suite.transport.responses["https://api.github.com/repos/john_doe/one"] = &Response{StatusCode: http.StatusMovedPermanently, Header: foreign}
+
+	for _, url := range []string{
+		"https://evil.example.com/repos/john_doe/one/languages",
+		"http://api.github.com/repos/john_doe/one/languages",
+		"https://api.github.com.evil.example.com/repos/john_doe/one/languages",
+		"https://user@api.github.com/repos/john_doe/one/languages",
+	} {
+		_, err := suite.github.GetRepositoryLanguages(url)
+		assert.Error(suite.T(), err, url)
+	}
+	_, err := suite.github.GetRepository("https://api.github.com/repos/john_doe/one")
+
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(suite.transport.requests))
+
+	guard := newURLGuard("https://ghe.corp/api/v3/", "https://ghe.corp/api/graphql")
+	assert.True(suite.T(), guard.allows("https://ghe.corp/api/v3/repositories?since=1"))
+	assert.True(suite.T(), guard.allows("https://GHE.corp/api/graphql"))
+	assert.False(suite.T(), guard.allows("https://ghe.corp/api/v3/../../admin"))
+	assert.False(suite.T(), guard.allows("https://ghe.corp/api/v30/repositories"))
+}
+
+func (suite *GithubSuite) TestGetRepositoryLanguages_NotModified() {
+	url := "https://api.github.com/repos/john_doe/one/languages"
+	suite.github.conditional = newConditionalCache(10)
+	header := http.Header{}
+	header.Set(ETagHeader, `"abc"`)
+	header.Set(LastModifiedHeader, "Mon, 01 Jan 2024 00:00:00 GMT")
+	suite.transport.responses[url] = &Response{StatusCode: http.StatusOK, Header: header, Body: []byte(`{"Go":42}`)}
+
+	first, err := suite.github.GetRepositoryLanguages(url)
+	assert.NoError(suite.T(), err)
+
+	suite.transport.responses[url] = &Response{StatusCode: http.StatusNotModified, Header: http.Header{}}
+	second, err := suite.github.GetRepositoryLanguages(url)
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), first, second)
+	assert.Equal(suite.T(), `"abc"`, suite.transport.requests[1].Header.Get(IfNoneMatchHeader))
+	assert.Equal(suite.T(), "Mon, 01 Jan 2024 00:00:00 GMT", suite.transport.requests[1].Header.Get(IfModifiedSinceHeader))
+}
+
+// TestGetRepositoryLanguages_NotModifiedEvicted sends the request again when the cached body is gone by the time the 304 comes
+func (suite *GithubSuite) TestGetRepositoryLanguages_NotModifiedEvicted() {
+	url := "https://api.github.com/repos/john_doe/one/languages"
+	suite.github.conditional = newConditionalCache(1)
+	suite.github.conditional.set(&conditionalEntry{uri: url, etag: `"abc"`, body: []byte(`{"Go":1}`)})
+	suite.transport.handler = func(req *Request) *Response {
+		if req.Header.Get(IfNoneMatchHeader) != "" {
+			// A concurrent request evicts the entry before the 304 comes back
+			suite.github.conditional.set(&conditionalEntry{uri: "https://api.github.com/repos/jane_doe/two", etag: `"def"`})
+			return &Response{StatusCode: http.StatusNotModified, Header: http.Header{}}
+		}
+		return &Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: []byte(`{"Go":42}`)}
+	}
+
+	languages, err := suite.github.GetRepositoryLanguages(url)
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), map[string]int{"Go": 42}, languages)
+	assert.Equal(suite.T(), 2, len(suite.transport.requests))
+	assert.Equal(suite.T(), `"abc"`, suite.transport.requests[0].Header.Get(IfNoneMatchHeader))
+	assert.Empty(suite.T(), suite.transport.requests[1].Header.Get(IfNoneMatchHeader))
+}
+
+func (suite *GithubSuite) TestConditionalCache_LRU() {
+	cache := newConditionalCache(2)
+	header := http.Header{}
+	header.Set(ETagHeader, `"abc"`)
+	for _, path := range []string{"repos/a/one", "repos/a/two", "repositories?since=1"} {
+		cache.resolve("https://api.github.com/"+path, &Response{StatusCode: http.StatusOK, Header: header})
+	}
+	cache.prepare("https://api.github.com/repos/a/one", http.Header{})
+	cache.resolve("https://api.github.com/repos/a/three", &Response{StatusCode: http.StatusOK, Header: header})
+
+	// The listing page isn't remembered and the least recently used entry is evicted
+	_, ok := cache.resolve("https://api.github.com/repos/a/two", &Response{StatusCode: http.StatusNotModified})
+	assert.False(suite.T(), ok)
+	_, ok = cache.resolve("https://api.github.com/repositories?since=1", &Response{StatusCode: http.StatusNotModified})
+	assert.False(suite.T(), ok)
+	_, ok = cache.resolve("https://api.github.com/repos/a/one", &Response{StatusCode: http.StatusNotModified})
+	assert.True(suite.T(), ok)
+}
+
+func (suite *GithubSuite) TestTokenPool_Rotation() {
+	url := "https://api.github.com/repos/john_doe/one/languages"
+	pool := newTokenPool([]string{"first", "second", "first", ""})
+	suite.github.tokens, suite.github.credentials = pool, pool
+
+	reset := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	header := http.Header{}
+	header.Set(RateLimitRemainingHeader, "0")
+	header.Set(RateLimitResetHeader, reset)
+	suite.transport.responses[url] = &Response{StatusCode: http.StatusForbidden, Header: header}
+
+	_, _ = suite.github.GetRepositoryLanguages(url)
+	suite.transport.responses[url] = &Response{StatusCode: http.StatusUnauthorized, Header: http.Header{}}
+	_, _ = suite.github.GetRepositoryLanguages(url)
+	_, err := suite.github.GetRepositoryLanguages(url)
+
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), 2, len(suite.transport.requests))
+	assert.Equal(suite.T(), "Bearer first", suite.transport.requests[0].Header.Get(Authorization))
+	assert.Equal(suite.T(), "Bearer second", suite.transport.requests[1].Header.Get(Authorization))
+
+	usage := suite.github.TokenUsage()
+	assert.Equal(suite.T(), 2, len(usage))
+	assert.Equal(suite.T(), "exhausted", usage[0].DisabledReason)
+	assert.Equal(suite.T(), "unauthorized", usage[1].DisabledReason)
+	for _, tokenUsage := range usage {
+		assert.False(suite.T(), tokenUsage.Active)
+		assert.NotContains(suite.T(), tokenUsage.ID, "first")
+		assert.NotContains(suite.T(), tokenUsage.ID, "second")
+	}
+}
+
+func (suite *GithubSuite) TestAppCredentials_InstallationToken() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(suite.T(), err)
+	keyFile := filepath.Join(suite.T().TempDir(), "app.pem")
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0o600)
+	assert.NoError(suite.T(), err)
+
+	exchanges := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app/installations/42/access_tokens":
+			exchanges++
+			jwt := strings.Split(strings.TrimPrefix(r.Header.Get(Authorization), Bearer), ".")
+			assert.Equal(suite.T(), 3, len(jwt))
+			signature, decodeErr := base64.RawURLEncoding.DecodeString(jwt[2])
+			assert.NoError(suite.T(), decodeErr)
+			digest := sha256.Sum256([]byte(jwt[0] + "." + jwt[1]))
+			assert.NoError(suite.T(), rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"token":"ghs_` + strconv.Itoa(exchanges) + `","expires_at":"` +
+				time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `"}`))
+		default:
+			assert.Equal(suite.T(), "Bearer ghs_"+strconv.Itoa(exchanges), r.Header.Get(Authorization))
+			_, _ = w.Write([]byte(`{"Go":42}`))
+		}
+	}))
+	defer server.Close()
+
+	transport, err := newTransport(&config.Config{GitHubTransport: NetHTTPTransport})
+	assert.NoError(suite.T(), err)
+	credentials, err := newAppCredentials("1234", "42", keyFile, func(path string) string { return server.URL + "/" + path }, "2022-11-28", transport)
+	assert.NoError(suite.T(), err)
+	suite.github.URL, suite.github.Transport, suite.github.credentials = server.URL+"/", transport, credentials
+
+	_, err = suite.github.GetRepositoryLanguages(server.URL + "/repos/john_doe/one/languages")
+	assert.NoError(suite.T(), err)
+	_, err = suite.github.GetRepositoryLanguages(server.URL + "/repos/john_doe/one/languages")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, exchanges)
+
+	// Shortly before the expiration the token is renewed
+	credentials.now = func() time.Time { return time.Now().Add(56 * time.Minute) }
+	_, err = suite.github.GetRepositoryLanguages(server.URL + "/repos/john_doe/one/languages")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, exchanges)
+}
+
+func (suite *GithubSuite) TestNetHTTPTransport_CABundle() {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(suite.T(), "Bearer secret", r.Header.Get(Authorization))
+		_, _ = w.Write([]byte(`{"Go":42}`))
+	}))
+	defer server.Close()
+
+	bundle := filepath.Join(suite.T().TempDir(), "ca.pem")
+	err := os.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600)
+	assert.NoError(suite.T(), err)
+
+	transport, err := newTransport(&config.Config{GitHubTransport: NetHTTPTransport, GitHubCABundle: bundle, GitHubHTTP2: true})
+	assert.NoError(suite.T(), err)
+	suite.github.URL, suite.github.Transport = server.URL+"/", transport
+
+	languages, err := suite.github.GetRepositoryLanguages(server.URL + "/repos/john_doe/one/languages")
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), map[string]int{"Go": 42}, languages)
+}
+
+func (suite *GithubSuite) TestNetHTTPTransport_UnknownAuthority() {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	server.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	transport, err := newTransport(&config.Config{GitHubTransport: NetHTTPTransport})
+	assert.NoError(suite.T(), err)
+	suite.github.URL, suite.github.Transport = server.URL+"/", transport
+
+	_, err = suite.github.GetRepositoryLanguages(server.URL + "/repos/john_doe/one/languages")
+
+	assert.Error(suite.T(), err)
+}
+
+func (suite *GithubSuite) TestSend_CoalescesInFlightRequests() {
+	suite.github.inflight = shared.NewCoalescer("GitHub request")
+	release := make(chan struct{})
+	suite.transport.handler = func(req *Request) *Response {
+		<-release
+		return &Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: []byte(`{"Go":100}`)}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(5)
+	for i := 0; i < 5; i++ {
+		go func() {
+			defer wg.Done()
+			languages, err := suite.github.GetRepositoryLanguages("https://api.github.com/repos/john_doe/one/languages")
+			assert.NoError(suite.T(), err)
+			assert.Equal(suite.T(), map[string]int{"Go": 100}, languages)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(suite.T(), 1, len(suite.transport.requests))
+	assert.Equal(suite.T(), int64(4), suite.github.inflight.Saved())
+}
+
+func TestGithubSuite(t *testing.T) {
+	s.Run(t, new(GithubSuite))
+}
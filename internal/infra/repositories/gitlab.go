@@ -0,0 +1,211 @@
+package repositories
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"scalingo/internal/core/dto"
+	conf "scalingo/internal/infra/config"
+	"scalingo/internal/shared"
+
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	GitlabSource = "gitlab"
+
+	GitlabProjectsEndpoint = "projects"
+	GitlabTokenHeader      = "PRIVATE-TOKEN"
+
+	// Keyset pagination over the public projects, ordered by id
+	gitlabListQuery = "?order_by=id&sort=asc&per_page=100&id_after="
+	gitlabHeadQuery = "?order_by=id&sort=desc&per_page=100"
+)
+
+// Gitlab is the repository source of a GitLab instance, gitlab.com or a self-managed one, built on its REST API v4
+type Gitlab struct {
+	Name      string
+	URL       string
+	Token     string
+	Transport Transport
+
+	guard *urlGuard
+}
+
+type gitlabProject struct {
+	ID                int             `json:"id"`
+	Name              string          `json:"name"`
+	PathWithNamespace string          `json:"path_with_namespace"`
+	Namespace         gitlabNamespace `json:"namespace"`
+	WebURL            string          `json:"web_url"`
+	Description       *string         `json:"description"` // null for the projects without description
+	ForkedFromProject *struct{}       `json:"forked_from_project"`
+	License           *gitlabLicense  `json:"license"`
+}
+
+type gitlabNamespace struct {
+	FullPath string `json:"full_path"`
+}
+
+type gitlabLicense struct {
+	Key string `json:"key"`
+}
+
+// ProvideGitlab builds the GitLab source, nil when no GitLab instance is configured
+func ProvideGitlab(config *conf.Config) *Gitlab {
+	if config.GitLabURL == "" {
+		return nil
+	}
+	return newGitlab(config, GitlabSource, config.GitLabURL, config.GitLabToken)
+}
+
+func newGitlab(config *conf.Config, name, url, token string) *Gitlab {
+	transport, err := newTransport(config)
+	if err != nil {
+		panic("Error initializing GitLab transport: " + err.Error())
+	}
+
+	gitlab := &Gitlab{
+		Name:      name,
+		URL:       normalizeBaseURL(url),
+		Token:     token,
+		Transport: transport,
+	}
+	gitlab.guard = newURLGuard(gitlab.URL)
+	return gitlab
+}
+
+// GetLatestRepoID returns the ID preceding the latest page of projects, the first id_after request then lists that page
+func (g *Gitlab) GetLatestRepoID() (int, error) {
+	statusCode, body, err := g.httpRequest(g.URL + GitlabProjectsEndpoint + gitlabHeadQuery)
+	if err != nil {
+		return 0, errors.New("error while getting latest project id: " + strconv.Itoa(statusCode) + shared.Separator + err.Error())
+	}
+
+	var projects []gitlabProject
+	if err = jsoniter.Unmarshal(body, &projects); err != nil {
+		return 0, errors.New("error while deserializing latest project id: " + err.Error())
+	}
+	if len(projects) == 0 {
+		return 0, nil
+	}
+
+	lowest := projects[0].ID
+	for _, project := range projects {
+		lowest = min(lowest, project.ID)
+	}
+	return lowest - 1, nil
+}
+
+func (g *Gitlab) GetRepositories(id int) ([]*dto.LatestCreatedRepo, int, error) {
+	statusCode, body, err := g.httpRequest(g.URL + GitlabProjectsEndpoint + gitlabListQuery + strconv.Itoa(id))
+	if err != nil {
+		return nil, id, errors.New("error while getting projects: " + strconv.Itoa(statusCode) + shared.Separator + err.Error())
+	}
+
+	var projects []gitlabProject
+	if err = jsoniter.Unmarshal(body, &projects); err != nil {
+		return nil, id, errors.New("error while deserializing projects: " + strconv.Itoa(statusCode) + shared.Separator + err.Error())
+	}
+
+	repos := make([]*dto.LatestCreatedRepo, 0, len(projects))
+	next := id
+	for i := range projects {
+		project := &projects[i]
+		next = max(next, project.ID)
+		if project.ForkedFromProject != nil { // excluding forks
+			continue
+		}
+		repos = append(repos, g.toLatestCreatedRepo(project))
+	}
+	return repos, next, nil
+}
+
+func (g *Gitlab) toLatestCreatedRepo(project *gitlabProject) *dto.LatestCreatedRepo {
+	projectURL := g.URL + GitlabProjectsEndpoint + "/" + strconv.Itoa(project.ID)
+	return &dto.LatestCreatedRepo{
+		ID:           project.ID,
+		Name:         project.Name,
+		FullName:     project.PathWithNamespace,
+		Owner:        &dto.Owner{Login: project.Namespace.FullPath},
+		HTMLURL:      project.WebURL,
+		LanguagesURL: projectURL + "/languages",
+		URL:          projectURL + "?license=true",
+		Description:  deref(project.Description),
+	}
+}
+
+// GetRepositoryLanguages returns the languages of the project in basis points, GitLab reporting percentages with two
+// decimals instead of bytes
+func (g *Gitlab) GetRepositoryLanguages(fullURL string) (map[string]int, error) {
+	statusCode, body, err := g.httpRequest(fullURL)
+	if err != nil {
+		return map[string]int{},
+			errors.New("error while getting project languages: " + strconv.Itoa(statusCode) + shared.Separator + err.Error())
+	}
+	if unavailable(statusCode) {
+		log.Warnf("Language not found for %s, skipping...", fullURL)
+		return map[string]int{}, nil
+	}
+
+	var percentages map[string]float64
+	if err = jsoniter.Unmarshal(body, &percentages); err != nil {
+		return map[string]int{},
+			errors.New("error while deserializing project languages: " + strconv.Itoa(statusCode) + shared.Separator + err.Error())
+	}
+
+	languages := make(map[string]int, len(percentages))
+	for language, percentage := range percentages {
+		languages[language] = int(math.Round(percentage * 100))
+	}
+	return languages, nil
+}
+
+// GetRepository returns the license of the project, along with the unit of its languages
+func (g *Gitlab) GetRepository(fullURL string) (*dto.RepositoryDetails, error) {
+	details := &dto.RepositoryDetails{LanguagesUnit: dto.BasisPointsUnit}
+	statusCode, body, err := g.httpRequest(fullURL)
+	if err != nil {
+		return details, errors.New("error while getting project: " + strconv.Itoa(statusCode) + shared.Separator + err.Error())
+	}
+	if unavailable(statusCode) {
+		log.Warnf("Project not found for %s, skipping...", fullURL)
+		return details, nil
+	}
+
+	var project gitlabProject
+	if err = jsoniter.Unmarshal(body, &project); err != nil {
+		return details, errors.New("error while deserializing project: " + strconv.Itoa(statusCode) + shared.Separator + err.Error())
+	}
+
+	details.FullName, details.HTMLURL = project.PathWithNamespace, project.WebURL
+	if project.License != nil {
+		details.License = project.License.Key
+	}
+	return details, nil
+}
+
+// unavailable tells whether the project can't be read, deleted or private and archived ones being answered with a 403
+func unavailable(statusCode int) bool {
+	return statusCode == http.StatusNotFound || statusCode == http.StatusForbidden
+}
+
+func (g *Gitlab) httpRequest(uri string) (statusCode int, body []byte, err error) {
+	if !g.guard.allows(uri) {
+		log.Errorf("Refusing to request %s, outside of the GitLab API %s", uri, g.URL)
+		return http.StatusInternalServerError, []byte{}, errors.New("refusing to request " + uri + ": outside of the GitLab API")
+	}
+
+	header := http.Header{}
+	if g.Token != "" {
+		header.Set(GitlabTokenHeader, g.Token)
+	}
+	resp, err := g.Transport.Do(&Request{Method: http.MethodGet, URL: uri, Header: header})
+	if err != nil {
+		return http.StatusInternalServerError, []byte{}, err
+	}
+	return resp.StatusCode, resp.Body, nil
+}
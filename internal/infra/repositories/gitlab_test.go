@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"scalingo/internal/core/dto"
+	"scalingo/internal/infra/config"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	s "github.com/stretchr/testify/suite"
+)
+
+type GitlabSuite struct {
+	s.Suite
+	server   *httptest.Server
+	projects []map[string]any
+	gitlab   *Gitlab
+}
+
+// gitlabStub imitates the projects endpoints of the GitLab REST API v4, with the id_after keyset pagination
+func (suite *GitlabSuite) gitlabStub(w http.ResponseWriter, r *http.Request) {
+	assert.Equal(suite.T(), "secret", r.Header.Get(GitlabTokenHeader))
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/v4/projects")
+	switch {
+	case path == "":
+		query := r.URL.Query()
+		assert.Equal(suite.T(), "id", query.Get("order_by"))
+		perPage, _ := strconv.Atoi(query.Get("per_page"))
+		idAfter, _ := strconv.Atoi(query.Get("id_after"))
+
+		projects := make([]map[string]any, 0)
+		for _, project := range suite.projects {
+			if project["id"].(int) > idAfter {
+				projects = append(projects, project)
+			}
+		}
+		if query.Get("sort") == "desc" {
+			sort.Slice(projects, func(i, j int) bool { return projects[i]["id"].(int) > projects[j]["id"].(int) })
+		}
+		suite.write(w, projects[:min(perPage, len(projects))])
+	case path == "/1/languages":
+		_, _ = w.Write([]byte(`{"Go":66.67,"Shell":33.03,"Makefile":0.3}`))
+	case path == "/2/languages", path == "/2":
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message":"403 Forbidden"}`))
+	case path == "/1":
+		assert.Equal(suite.T(), "true", r.URL.Query().Get("license"))
+		suite.write(w, suite.projects[0])
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"404 Project Not Found"}`))
+	}
+}
+
+func (suite *GitlabSuite) write(w http.ResponseWriter, v any) {
+	body, err := jsoniter.Marshal(v)
+	assert.NoError(suite.T(), err)
+	_, _ = w.Write(body)
+}
+
+func (suite *GitlabSuite) SetupTest() {
+	suite.projects = []map[string]any{
+		{
+			"id": 1, "name": "one", "path_with_namespace": "john_doe/one", "namespace": map[string]any{"full_path": "john_doe"},
+			"web_url": "https://gitlab.com/john_doe/one", "description": nil, "license": map[string]any{"key": "mit"},
+		},
+		{
+			"id": 2, "name": "two", "path_with_namespace": "jane_doe/two", "namespace": map[string]any{"full_path": "jane_doe"},
+			"web_url": "https://gitlab.com/jane_doe/two", "description": "forked", "forked_from_project": map[string]any{"id": 1},
+		},
+		{
+			"id": 3, "name": "three", "path_with_namespace": "group/sub/three", "namespace": map[string]any{"full_path": "group/sub"},
+			"web_url": "https://gitlab.com/group/sub/three", "description": "nested",
+		},
+	}
+	suite.server = httptest.NewServer(http.HandlerFunc(suite.gitlabStub))
+	suite.gitlab = newGitlab(&config.Config{GitHubTransport: NetHTTPTransport}, GitlabSource, suite.server.URL+"/api/v4", "secret")
+}
+
+func (suite *GitlabSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *GitlabSuite) TestGetLatestRepoID() {
+	id, err := suite.gitlab.GetLatestRepoID()
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, id)
+}
+
+func (suite *GitlabSuite) TestGetRepositories_KeysetExcludesForks() {
+	repos, next, err := suite.gitlab.GetRepositories(0)
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, len(repos))
+	assert.Equal(suite.T(), 3, next)
+	assert.Equal(suite.T(), "john_doe/one", repos[0].FullName)
+	assert.Equal(suite.T(), "john_doe", repos[0].Owner.Login)
+	assert.Equal(suite.T(), "", repos[0].Description)
+	assert.Equal(suite.T(), "group/sub", repos[1].Owner.Login)
+	assert.Equal(suite.T(), suite.server.URL+"/api/v4/projects/3/languages", repos[1].LanguagesURL)
+
+	repos, _, err = suite.gitlab.GetRepositories(1)
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(repos))
+	assert.Equal(suite.T(), 3, repos[0].ID)
+}
+
+func (suite *GitlabSuite) TestGetRepositoryLanguagesAndLicense() {
+	repos, _, err := suite.gitlab.GetRepositories(0)
+	assert.NoError(suite.T(), err)
+
+	languages, err := suite.gitlab.GetRepositoryLanguages(repos[0].LanguagesURL)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), map[string]int{"Go": 6667, "Shell": 3303, "Makefile": 30}, languages)
+
+	details, err := suite.gitlab.GetRepository(repos[0].URL)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "mit", details.License)
+	assert.Equal(suite.T(), "john_doe/one", details.FullName)
+	assert.Equal(suite.T(), dto.BasisPointsUnit, details.LanguagesUnit)
+
+	details, err = suite.gitlab.GetRepository(repos[1].URL)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "", details.License)
+}
+
+func (suite *GitlabSuite) TestGetRepositoryLanguagesAndLicense_Forbidden() {
+	languages, err := suite.gitlab.GetRepositoryLanguages(suite.server.URL + "/api/v4/projects/2/languages")
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), languages)
+
+	details, err := suite.gitlab.GetRepository(suite.server.URL + "/api/v4/projects/2?license=true")
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), details.License)
+}
+
+func (suite *GitlabSuite) TestGuard_RefusesOtherHosts() {
+	_, err := suite.gitlab.GetRepository("https://evil.example.com/api/v4/projects/1")
+
+	assert.Error(suite.T(), err)
+}
+
+func TestGitlabSuite(t *testing.T) {
+	s.Run(t, new(GitlabSuite))
+}
@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// urlGuard is the allowlist of the outbound URLs, derived from the configured API roots,
+// it keeps the credentials away from any host an upstream answer or a proxy could point to
+type urlGuard struct {
+	roots []*url.URL
+}
+
+func newURLGuard(roots ...string) *urlGuard {
+	guard := &urlGuard{}
+	for _, root := range roots {
+		guard.allow(root)
+	}
+	return guard
+}
+
+// allow adds an API root, every URL under its scheme, host and path is then allowed
+func (u *urlGuard) allow(root string) {
+	parsed, err := url.Parse(root)
+	if err != nil || parsed.Host == "" {
+		return
+	}
+	parsed.Path = strings.TrimSuffix(path.Clean("/"+parsed.Path), "/")
+	u.roots = append(u.roots, parsed)
+}
+
+func (u *urlGuard) allows(uri string) bool {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.User != nil || parsed.Host == "" {
+		return false
+	}
+
+	cleaned := path.Clean("/" + parsed.Path)
+	for _, root := range u.roots {
+		if !strings.EqualFold(parsed.Scheme, root.Scheme) || !strings.EqualFold(parsed.Host, root.Host) {
+			continue
+		}
+		if cleaned == root.Path || strings.HasPrefix(cleaned, root.Path+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package repositories
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+
+	"scalingo/internal/shared"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	EventsHeadStrategy = "events"
+	SearchHeadStrategy = "search"
+
+	// First gallop of the head search, doubled until a probe comes back empty
+	headSearchInitialStep = 1 << 16
+	// Bound of the probes of a single head search, enough to cover the whole int range
+	headSearchMaxProbes = 128
+)
+
+// Concurrent safe last known head, the next searches start from it and only have to cover the repositories created since
+type knownHead struct {
+	sync.Mutex
+	ID int
+}
+
+func (k *knownHead) get() int {
+	k.Lock()
+	defer k.Unlock()
+	return k.ID
+}
+
+func (k *knownHead) set(id int) {
+	k.Lock()
+	defer k.Unlock()
+	k.ID = max(k.ID, id)
+}
+
+// GetLatestRepoID returns the ID of the latest created repository, instantly when the events are polled in the background,
+// otherwise found with the configured strategy, the events based one being the fallback of the binary search.
+// The binary search finds the head itself, listing since it would be empty, so a cursor one page behind it is returned
+func (g *Github) GetLatestRepoID() (int, error) {
+	if g.events != nil {
+		if id, ok := g.events.LatestCreatedRepoID(); ok {
+			g.head.set(id)
+			return id, nil
+		}
+	}
+
+	if g.HeadStrategy == SearchHeadStrategy {
+		id, err := g.searchHead()
+		if err == nil {
+			return max(id-pageSize, 0), nil
+		}
+		log.Warnf("couldn't search the latest repo ID, falling back to the events: %s", err.Error())
+	}
+
+	id, err := g.eventsHead()
+	if err != nil {
+		return 0, err
+	}
+	g.head.set(id)
+	return id, nil
+}
+
+func (g *Github) eventsHead() (int, error) {
+	for i := 0; i < g.LatestCreatedRepoRetry; i++ {
+		log.Warnf("try %d on %d to fetch latest created repo ID", i, g.LatestCreatedRepoRetry)
+		statusCode, b, err := g.httpRequest(g.endpoint(EventsEndpoint))
+		if err != nil {
+			return 0, errors.New("error while getting latest repo id: " + strconv.Itoa(statusCode) + shared.Separator + err.Error())
+		}
+
+		id, found, err := decodeLatestCreatedRepoID(b)
+		if err != nil {
+			return 0, errors.New("error while deserializing latest repo id: " + err.Error())
+		}
+		if found {
+			log.Warnf("CreateEvent for %s detected...", RepositoryRefType)
+			return id, nil
+		}
+	}
+
+	return 0, errors.New("error while getting latest repo id: couldn't find latest ID")
+}
+
+// searchHead finds the highest existing repository ID with /repositories?since=, which is never delayed like the events feed.
+// It gallops from the last known head until a probe comes back empty, then binary searches between the highest ID
+// seen and the empty probe, until a probe comes back with a page which isn't full and so ends at the head
+func (g *Github) searchHead() (int, error) {
+	known := g.head.get()
+	empty := -1
+	step := headSearchInitialStep
+
+	for probes := 0; empty < 0 || known < empty; probes++ {
+		if probes >= headSearchMaxProbes {
+			return 0, errors.New("error while searching latest repo id: too many probes")
+		}
+
+		since := known + step
+		if empty >= 0 {
+			since = known + (empty-known)/2
+		}
+
+		maxID, count, err := g.maxRepositoryIDSince(since)
+		if err != nil {
+			return 0, err
+		}
+
+		switch {
+		case maxID > 0 && count < pageSize:
+			known, empty = maxID, maxID
+		case maxID > 0:
+			known = maxID
+			if empty < 0 {
+				step *= 2
+			}
+		case since == known:
+			empty = known
+		default:
+			empty = since
+		}
+	}
+
+	log.Infof("Latest repo ID %d found by binary search", known)
+	g.head.set(known)
+	return known, nil
+}
+
+func (g *Github) maxRepositoryIDSince(since int) (maxID, count int, err error) {
+	statusCode, b, err := g.httpRequest(g.endpoint(RepoListEndpoint) + Since + strconv.Itoa(since))
+	if err != nil {
+		return 0, 0, errors.New("error while searching latest repo id: " + strconv.Itoa(statusCode) + shared.Separator + err.Error())
+	}
+
+	maxID, count, err = decodeMaxRepositoryID(b)
+	if err != nil {
+		return 0, 0, errors.New("error while deserializing repositories: " + strconv.Itoa(statusCode) + shared.Separator + err.Error())
+	}
+	return maxID, count, nil
+}
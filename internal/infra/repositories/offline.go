@@ -0,0 +1,302 @@
+package repositories
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"scalingo/internal/core/dto"
+	"scalingo/internal/core/port"
+	conf "scalingo/internal/infra/config"
+	"scalingo/internal/infra/snapshot"
+
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	OnlineMode  = "online"
+	OfflineMode = "offline"
+
+	defaultAPIURL     = "https://api.github.com/"
+	reposPath         = "/repos/"
+	languagesSegment  = "languages"
+	snapshotExtension = ".jsonl.zst"
+	recordedExtension = ".json"
+)
+
+// Offline serves the GitHub endpoints from a directory instead of the API, for the demos and the CI. The directory holds
+// snapshot archives (*.jsonl.zst) and recorded answers in the GitHub format: repositories.json for /repositories,
+// events.json for /events, repos/{owner}/{repo}.json and repos/{owner}/{repo}/languages.json
+type Offline struct {
+	Dir string
+	URL string
+
+	// IDs of every listed repository, forks included, as GitHub pages through them
+	ids          []int
+	repositories map[int]*dto.LatestCreatedRepo
+	languages    map[string]map[string]int         // by lowercased owner/repo
+	details      map[string]*dto.RepositoryDetails // by lowercased owner/repo
+	events       []*dto.Event                      // newest first
+}
+
+// ProvideOffline loads the offline directory, nil unless GITHUB_MODE=offline
+func ProvideOffline(config *conf.Config) *Offline {
+	if config.GitHubMode != OfflineMode {
+		return nil
+	}
+
+	url := config.GitHubURL
+	if url == "" {
+		url = defaultAPIURL
+	}
+	offline, err := NewOffline(config.GitHubOfflineDir, url)
+	if err != nil {
+		panic("Error loading the offline GitHub directory: " + err.Error())
+	}
+	return offline
+}
+
+func NewOffline(dir, url string) (*Offline, error) {
+	if dir == "" {
+		return nil, errors.New("GITHUB_OFFLINE_DIR is required in offline mode")
+	}
+
+	o := &Offline{
+		Dir:          dir,
+		URL:          normalizeBaseURL(url),
+		repositories: make(map[int]*dto.LatestCreatedRepo),
+		languages:    make(map[string]map[string]int),
+		details:      make(map[string]*dto.RepositoryDetails),
+		events:       make([]*dto.Event, 0),
+	}
+	if err := o.loadSnapshots(); err != nil {
+		return nil, err
+	}
+	if err := o.loadRecorded(); err != nil {
+		return nil, err
+	}
+	sort.Ints(o.ids)
+
+	log.Infof("Offline GitHub: %d repositories and %d events loaded from %s", len(o.repositories), len(o.events), dir)
+	return o, nil
+}
+
+// loadSnapshots rebuilds the GitHub answers of the repositories of the snapshots, a renamed repository is listed
+// under its previous name and its details answer the current one
+func (o *Offline) loadSnapshots() error {
+	files, err := filepath.Glob(filepath.Join(o.Dir, "*"+snapshotExtension))
+	if err != nil {
+		return err
+	}
+
+	archive := snapshot.ProvideArchive()
+	for _, file := range files {
+		r, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		decoded, err := archive.Read(r)
+		_ = r.Close()
+		if err != nil {
+			return errors.New("error while reading snapshot " + file + ": " + err.Error())
+		}
+
+		for _, stored := range decoded.Repositories {
+			if stored.Source != port.DefaultSource || stored.Repository == nil {
+				continue
+			}
+			repository := stored.Repository
+			listed := repository.FullName
+			if repository.PreviousFullName != "" {
+				listed = repository.PreviousFullName
+			}
+			owner, name, _ := strings.Cut(listed, "/")
+			apiURL := o.URL + "repos/" + listed
+
+			o.add(&dto.LatestCreatedRepo{
+				ID:           stored.ID,
+				Name:         name,
+				FullName:     listed,
+				Owner:        &dto.Owner{Login: owner},
+				HTMLURL:      repository.Repository,
+				LanguagesURL: apiURL + "/" + languagesSegment,
+				URL:          apiURL,
+				Description:  repository.Description,
+			})
+			key := strings.ToLower(listed)
+			o.languages[key] = repository.Languages
+			o.details[key] = &dto.RepositoryDetails{
+				FullName: repository.FullName,
+				HTMLURL:  repository.Repository,
+				License:  repository.License,
+			}
+		}
+	}
+	return nil
+}
+
+func (o *Offline) loadRecorded() error {
+	if body, err := os.ReadFile(filepath.Join(o.Dir, RepoListEndpoint+recordedExtension)); err == nil {
+		var ids []struct {
+			ID int `json:"id"`
+		}
+		if err = jsoniter.Unmarshal(body, &ids); err != nil {
+			return errors.New("error while deserializing recorded repositories: " + err.Error())
+		}
+		repositories, _, err := decodeRepositories(body)
+		if err != nil {
+			return errors.New("error while deserializing recorded repositories: " + err.Error())
+		}
+		for _, item := range ids {
+			if _, ok := o.repositories[item.ID]; !ok {
+				o.ids = append(o.ids, item.ID)
+				o.repositories[item.ID] = nil // fork
+			}
+		}
+		for _, repository := range repositories {
+			o.repositories[repository.ID] = repository
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	if body, err := os.ReadFile(filepath.Join(o.Dir, EventsEndpoint+recordedExtension)); err == nil {
+		if o.events, err = decodeEvents(body); err != nil {
+			return errors.New("error while deserializing recorded events: " + err.Error())
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (o *Offline) add(repository *dto.LatestCreatedRepo) {
+	if _, ok := o.repositories[repository.ID]; !ok {
+		o.ids = append(o.ids, repository.ID)
+	}
+	o.repositories[repository.ID] = repository
+}
+
+// GetLatestRepoID returns the ID preceding the newest page of repositories, the first listing then returns that page
+func (o *Offline) GetLatestRepoID() (int, error) {
+	if len(o.ids) == 0 {
+		return 0, errors.New("error while getting latest repo id: no offline repository in " + o.Dir)
+	}
+	return o.ids[max(len(o.ids)-pageSize, 0)] - 1, nil
+}
+
+// GetRepositories pages through the repositories like GitHub does, the page of the IDs above since, forks excluded
+func (o *Offline) GetRepositories(since int) ([]*dto.LatestCreatedRepo, int, error) {
+	from := sort.SearchInts(o.ids, since+1)
+	to := min(from+pageSize, len(o.ids))
+	if from == to {
+		return []*dto.LatestCreatedRepo{}, since, nil
+	}
+
+	repos := make([]*dto.LatestCreatedRepo, 0, to-from)
+	for _, id := range o.ids[from:to] {
+		if repository := o.repositories[id]; repository != nil {
+			copied := *repository
+			repos = append(repos, &copied)
+		}
+	}
+	return repos, o.ids[to-1], nil
+}
+
+func (o *Offline) GetRepositoryLanguages(fullURL string) (map[string]int, error) {
+	owner, repo, ok := repositoryOf(fullURL, true)
+	if !ok {
+		log.Warnf("Language not found for %s, skipping...", fullURL)
+		return map[string]int{}, nil
+	}
+	if languages, ok := o.languages[strings.ToLower(owner+"/"+repo)]; ok {
+		return languages, nil
+	}
+
+	body, err := os.ReadFile(filepath.Join(o.Dir, "repos", owner, repo, languagesSegment+recordedExtension))
+	if err != nil {
+		log.Warnf("Language not found for %s, skipping...", fullURL)
+		return map[string]int{}, nil
+	}
+	var languages map[string]int
+	if err = jsoniter.Unmarshal(body, &languages); err != nil {
+		return map[string]int{}, errors.New("error while deserializing repository languages: " + err.Error())
+	}
+	return languages, nil
+}
+
+func (o *Offline) GetRepository(fullURL string) (*dto.RepositoryDetails, error) {
+	owner, repo, ok := repositoryOf(fullURL, false)
+	if !ok {
+		log.Warnf("Repository not found for %s, skipping...", fullURL)
+		return &dto.RepositoryDetails{}, nil
+	}
+	if details, ok := o.details[strings.ToLower(owner+"/"+repo)]; ok {
+		copied := *details
+		return &copied, nil
+	}
+
+	body, err := os.ReadFile(filepath.Join(o.Dir, "repos", owner, repo+recordedExtension))
+	if err != nil {
+		log.Warnf("Repository not found for %s, skipping...", fullURL)
+		return &dto.RepositoryDetails{}, nil
+	}
+	var details repositoryDetails
+	if err = jsoniter.Unmarshal(body, &details); err != nil {
+		return &dto.RepositoryDetails{}, errors.New("error while deserializing repository: " + err.Error())
+	}
+	return &dto.RepositoryDetails{
+		FullName: details.FullName,
+		HTMLURL:  details.HTMLURL,
+		License:  details.License["spdx_id"],
+	}, nil
+}
+
+// Recent returns the recorded events, oldest first
+func (o *Offline) Recent() []*dto.Event {
+	events := make([]*dto.Event, 0, len(o.events))
+	for i := len(o.events) - 1; i >= 0; i-- {
+		events = append(events, o.events[i])
+	}
+	return events
+}
+
+// Subscribe returns a channel receiving nothing, the recorded events never change
+func (o *Offline) Subscribe(buffer int) (events <-chan *dto.Event, unsubscribe func()) {
+	subscriber := make(chan *dto.Event, buffer)
+	return subscriber, func() { close(subscriber) }
+}
+
+// repositoryOf extracts the owner and repository of a /repos/{owner}/{repo} URL, or of its languages endpoint,
+// rejecting anything else so that the URL can't reach outside of the directory
+func repositoryOf(fullURL string, languages bool) (owner, repo string, ok bool) {
+	parsed, err := url.Parse(fullURL)
+	if err != nil {
+		return "", "", false
+	}
+	_, path, found := strings.Cut(parsed.Path, reposPath)
+	if !found {
+		return "", "", false
+	}
+
+	segments := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if languages {
+		if len(segments) != 3 || segments[2] != languagesSegment { //nolint: gomnd
+			return "", "", false
+		}
+		segments = segments[:2]
+	}
+	if len(segments) != 2 { //nolint: gomnd
+		return "", "", false
+	}
+	for _, segment := range segments {
+		if segment == "" || segment == "." || segment == ".." || strings.ContainsAny(segment, `\`) {
+			return "", "", false
+		}
+	}
+	return segments[0], segments[1], true
+}
@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"scalingo/internal/core/domain"
+	"scalingo/internal/infra/snapshot"
+
+	"github.com/stretchr/testify/assert"
+	s "github.com/stretchr/testify/suite"
+)
+
+type OfflineSuite struct {
+	s.Suite
+	dir     string
+	offline *Offline
+}
+
+func (suite *OfflineSuite) SetupTest() {
+	suite.dir = suite.T().TempDir()
+	for _, name := range []string{"repositories.json", "events.json"} {
+		body, err := os.ReadFile(filepath.Join("testdata", name))
+		assert.NoError(suite.T(), err)
+		assert.NoError(suite.T(), os.WriteFile(filepath.Join(suite.dir, name), body, 0o600))
+	}
+	assert.NoError(suite.T(), os.MkdirAll(filepath.Join(suite.dir, "repos", "user43445", "kit-cli-0"), 0o700))
+	assert.NoError(suite.T(), os.WriteFile(
+		filepath.Join(suite.dir, "repos", "user43445", "kit-cli-0", "languages.json"), []byte(`{"Go":1200,"Shell":30}`), 0o600))
+	assert.NoError(suite.T(), os.WriteFile(
+		filepath.Join(suite.dir, "repos", "user43445", "kit-cli-0.json"),
+		[]byte(`{"full_name":"user43445/kit","html_url":"https://github.com/user43445/kit","license":{"spdx_id":"MIT"}}`), 0o600))
+
+	file, err := os.Create(filepath.Join(suite.dir, "crawl.jsonl.zst"))
+	assert.NoError(suite.T(), err)
+	writer, err := snapshot.ProvideArchive().NewWriter(file, &domain.SnapshotHeader{Version: domain.SnapshotVersion})
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), writer.Write(&domain.StoredRepository{Source: "github", ID: 755000300, FetchedAt: time.Now(), Repository: &domain.ListRepoOutput{
+		FullName:         "jane_doe/renamed",
+		PreviousFullName: "jane_doe/original",
+		Repository:       "https://github.com/jane_doe/renamed",
+		License:          "Apache-2.0",
+		Languages:        map[string]int{"Rust": 500},
+	}}))
+	_, err = writer.Close()
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), file.Close())
+
+	suite.offline, err = NewOffline(suite.dir, "https://api.github.com/")
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *OfflineSuite) TestPaging() {
+	since, err := suite.offline.GetLatestRepoID()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 755000100, since)
+
+	// The newest page is made of the 100 IDs above since, the forks being excluded
+	page, _, err := suite.offline.GetRepositories(since)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 86, len(page))
+	assert.Equal(suite.T(), 755000101, page[0].ID)
+
+	page, _, err = suite.offline.GetRepositories(755000199)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(page))
+	assert.Equal(suite.T(), "jane_doe/original", page[0].FullName)
+
+	page, _, err = suite.offline.GetRepositories(755000300)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), page)
+}
+
+func (suite *OfflineSuite) TestRecordedAnswers() {
+	languages, err := suite.offline.GetRepositoryLanguages("https://api.github.com/repos/user43445/kit-cli-0/languages")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), map[string]int{"Go": 1200, "Shell": 30}, languages)
+
+	details, err := suite.offline.GetRepository("https://api.github.com/repos/user43445/kit-cli-0")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "user43445/kit", details.FullName)
+	assert.Equal(suite.T(), "MIT", details.License)
+
+	languages, err = suite.offline.GetRepositoryLanguages("https://api.github.com/repos/user43445/missing/languages")
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), languages)
+}
+
+func (suite *OfflineSuite) TestSnapshotAnswers() {
+	details, err := suite.offline.GetRepository("https://api.github.com/repos/jane_doe/original")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "jane_doe/renamed", details.FullName)
+	assert.Equal(suite.T(), "Apache-2.0", details.License)
+
+	languages, err := suite.offline.GetRepositoryLanguages("https://api.github.com/repos/Jane_Doe/Original/languages")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), map[string]int{"Rust": 500}, languages)
+}
+
+func (suite *OfflineSuite) TestOutsideOfTheDirectory() {
+	_, _, ok := repositoryOf("https://api.github.com/repos/../../etc/passwd", false)
+	assert.False(suite.T(), ok)
+	_, _, ok = repositoryOf("https://api.github.com/repos/user43445/kit-cli-0", true)
+	assert.False(suite.T(), ok)
+}
+
+func (suite *OfflineSuite) TestEvents() {
+	events := suite.offline.Recent()
+
+	assert.Equal(suite.T(), 30, len(events))
+	assert.Equal(suite.T(), "35000000029", events[0].ID)
+
+	channel, unsubscribe := suite.offline.Subscribe(1)
+	unsubscribe()
+	_, open := <-channel
+	assert.False(suite.T(), open)
+}
+
+func TestOfflineSuite(t *testing.T) {
+	s.Run(t, new(OfflineSuite))
+}
@@ -0,0 +1,170 @@
+package repositories
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"scalingo/internal/core/dto"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	RateLimitLimitHeader     = "X-RateLimit-Limit"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	RateLimitResetHeader     = "X-RateLimit-Reset"
+
+	// Authenticated GitHub quota, assumed for the tokens which haven't been used yet
+	defaultRateLimit = 5000
+	// Delay before a token rejected with a 401 and without any reset time is tried again
+	unauthorizedCooldown = time.Hour
+)
+
+// credentials provides the bearer token of every request and learns from the answers
+type credentials interface {
+	token() (string, error)
+	observe(token string, resp *Response)
+}
+
+type tokenState struct {
+	value          string
+	id             string
+	limit          int
+	remaining      int
+	reset          time.Time
+	requests       int
+	disabledUntil  time.Time
+	disabledReason string
+}
+
+// Concurrent safe pool of GitHub tokens, every request uses the token with the most remaining quota,
+// tokens exhausted or rejected are taken out of the rotation until their reset time
+type tokenPool struct {
+	sync.Mutex
+	tokens map[string]*tokenState
+	order  []*tokenState
+	now    func() time.Time
+}
+
+func newTokenPool(values []string) *tokenPool {
+	pool := &tokenPool{tokens: make(map[string]*tokenState), now: time.Now}
+	for _, value := range values {
+		if value == "" || pool.tokens[value] != nil {
+			continue
+		}
+		// The beginning of the hash is enough to tell the tokens apart without disclosing them
+		fingerprint := sha256.Sum256([]byte(value))
+		state := &tokenState{
+			value:     value,
+			id:        "token-" + strconv.Itoa(len(pool.order)+1) + "-" + hex.EncodeToString(fingerprint[:4]),
+			limit:     defaultRateLimit,
+			remaining: defaultRateLimit,
+		}
+		pool.tokens[value] = state
+		pool.order = append(pool.order, state)
+	}
+	return pool
+}
+
+func (p *tokenPool) token() (string, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	now := p.now()
+	var best *tokenState
+	var nextReset time.Time
+	for _, state := range p.order {
+		if now.Before(state.disabledUntil) {
+			if nextReset.IsZero() || state.disabledUntil.Before(nextReset) {
+				nextReset = state.disabledUntil
+			}
+			continue
+		}
+		if !state.disabledUntil.IsZero() {
+			// Back in the rotation, the quota has been restored in the meantime
+			state.disabledUntil, state.disabledReason = time.Time{}, ""
+			state.remaining = state.limit
+		}
+		if best == nil || state.remaining > best.remaining {
+			best = state
+		}
+	}
+
+	if best == nil {
+		if nextReset.IsZero() {
+			return "", errors.New("no GitHub token configured")
+		}
+		return "", errors.New("every GitHub token is out of rotation until " + nextReset.Format(time.RFC3339))
+	}
+
+	best.requests++
+	return best.value, nil
+}
+
+func (p *tokenPool) observe(token string, resp *Response) {
+	p.Lock()
+	defer p.Unlock()
+
+	state, ok := p.tokens[token]
+	if !ok {
+		return
+	}
+
+	if limit, err := strconv.Atoi(resp.Header.Get(RateLimitLimitHeader)); err == nil {
+		state.limit = limit
+	}
+	if remaining, err := strconv.Atoi(resp.Header.Get(RateLimitRemainingHeader)); err == nil {
+		state.remaining = remaining
+	}
+	if reset, err := strconv.ParseInt(resp.Header.Get(RateLimitResetHeader), 10, 64); err == nil {
+		state.reset = time.Unix(reset, 0)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		until := state.reset
+		if !until.After(p.now()) {
+			until = p.now().Add(unauthorizedCooldown)
+		}
+		p.disable(state, until, "unauthorized")
+	case state.remaining <= 0 && state.reset.After(p.now()):
+		p.disable(state, state.reset, "exhausted")
+	}
+}
+
+func (p *tokenPool) disable(state *tokenState, until time.Time, reason string) {
+	if state.disabledReason == "" {
+		log.Warnf("GitHub token %s %s, out of rotation until %s", state.id, reason, until.Format(time.RFC3339))
+	}
+	state.disabledUntil, state.disabledReason = until, reason
+}
+
+func (p *tokenPool) usage() []*dto.TokenUsage {
+	p.Lock()
+	defer p.Unlock()
+
+	now := p.now()
+	usage := make([]*dto.TokenUsage, 0, len(p.order))
+	for _, state := range p.order {
+		tokenUsage := &dto.TokenUsage{
+			ID:        state.id,
+			Limit:     state.limit,
+			Remaining: state.remaining,
+			Requests:  state.requests,
+			Active:    !now.Before(state.disabledUntil),
+		}
+		if !state.reset.IsZero() {
+			tokenUsage.Reset = state.reset.UTC().Format(time.RFC3339)
+		}
+		if !tokenUsage.Active {
+			tokenUsage.DisabledReason = state.disabledReason
+		}
+		usage = append(usage, tokenUsage)
+	}
+	return usage
+}
@@ -0,0 +1,194 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	conf "scalingo/internal/infra/config"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	FastHTTPTransport = "fasthttp"
+	NetHTTPTransport  = "nethttp"
+
+	idleConnTimeout     = 90 * time.Second
+	maxIdleConnsPerHost = 100
+)
+
+// ErrResponseTooLarge is returned for the answers above GITHUB_MAX_RESPONSE_SIZE, fasthttp returns its own fasthttp.ErrBodyTooLarge
+var ErrResponseTooLarge = errors.New("response body too large")
+
+// Request is the transport agnostic representation of an outbound call to GitHub
+type Request struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+// Response is the transport agnostic representation of a GitHub answer, the body is owned by the caller
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// Set when GitHub answered 304 Not Modified and the body comes from the conditional requests cache
+	NotModified bool
+}
+
+// Transport sends a request to GitHub without following redirects,
+// it allows swapping the HTTP client (or injecting a fake one in tests) without touching the adapter
+type Transport interface {
+	Do(req *Request) (*Response, error)
+}
+
+func newTransport(config *conf.Config) (Transport, error) {
+	switch config.GitHubTransport {
+	case "", FastHTTPTransport:
+		return &fastHTTPTransport{client: &fasthttp.Client{MaxResponseBodySize: config.GitHubMaxResponseSize}}, nil
+	case NetHTTPTransport:
+		return newNetHTTPTransport(config)
+	default:
+		return nil, errors.New("unknown GitHub transport: " + config.GitHubTransport)
+	}
+}
+
+type fastHTTPTransport struct {
+	client *fasthttp.Client
+}
+
+func (t *fastHTTPTransport) Do(r *Request) (*Response, error) {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(r.URL)
+	req.Header.SetMethod(r.Method)
+	for key, values := range r.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	if len(r.Body) > 0 {
+		req.SetBody(r.Body)
+	}
+
+	if err := t.client.Do(req, resp); err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	resp.Header.VisitAll(func(key, value []byte) {
+		header.Add(string(key), string(value))
+	})
+
+	// The response body belongs to the pooled fasthttp response, it has to be copied before the release
+	return &Response{
+		StatusCode: resp.StatusCode(),
+		Header:     header,
+		Body:       append([]byte(nil), resp.Body()...),
+	}, nil
+}
+
+type netHTTPTransport struct {
+	client          *http.Client
+	maxResponseSize int
+}
+
+// newNetHTTPTransport builds a net/http based transport which goes through HTTPS_PROXY (or GITHUB_PROXY),
+// trusts the optional CA bundle and negotiates HTTP/2 when enabled
+func newNetHTTPTransport(config *conf.Config) (*netHTTPTransport, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if config.GitHubCABundle != "" {
+		pem, err := os.ReadFile(config.GitHubCABundle)
+		if err != nil {
+			return nil, errors.New("error while reading CA bundle: " + err.Error())
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("error while reading CA bundle: no certificate found in " + config.GitHubCABundle)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if config.GitHubProxy != "" {
+		proxyURL, err := url.Parse(config.GitHubProxy)
+		if err != nil {
+			return nil, errors.New("error while parsing GitHub proxy: " + err.Error())
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	transport := &http.Transport{
+		Proxy:               proxy,
+		TLSClientConfig:     tlsConfig,
+		ForceAttemptHTTP2:   config.GitHubHTTP2,
+		MaxIdleConnsPerHost: maxIdleConnsPerHost,
+		IdleConnTimeout:     idleConnTimeout,
+	}
+	if !config.GitHubHTTP2 {
+		// A non-nil empty map is the documented way to disable HTTP/2
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return &netHTTPTransport{
+		client: &http.Client{
+			Transport: transport,
+			// Redirects are handled by the adapter, like with fasthttp
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		maxResponseSize: config.GitHubMaxResponseSize,
+	}, nil
+}
+
+func (t *netHTTPTransport) Do(r *Request) (*Response, error) {
+	var body io.Reader
+	if len(r.Body) > 0 {
+		body = bytes.NewReader(r.Body)
+	}
+	req, err := http.NewRequestWithContext(context.Background(), r.Method, r.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range r.Header {
+		req.Header[key] = append(req.Header[key], values...)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	reader := io.Reader(resp.Body)
+	if t.maxResponseSize > 0 {
+		reader = io.LimitReader(resp.Body, int64(t.maxResponseSize)+1)
+	}
+	b, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if t.maxResponseSize > 0 && len(b) > t.maxResponseSize {
+		return nil, ErrResponseTooLarge
+	}
+
+	return &Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       b,
+	}, nil
+}
@@ -0,0 +1,203 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"scalingo/internal/core/domain"
+	"scalingo/internal/core/port"
+	conf "scalingo/internal/infra/config"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// BM25 term frequency saturation and length normalization
+	k1 = 1.2
+	b  = 0.75
+
+	// Length of the description snippets
+	snippetSize = 160
+
+	NameField        = "name"
+	FullNameField    = "full_name"
+	DescriptionField = "description"
+)
+
+// Weights of the fields in the term frequencies, a term of the name counts more than one of the description
+var fieldWeights = map[string]float64{
+	NameField:        2,
+	FullNameField:    1,
+	DescriptionField: 1,
+}
+
+type docKey struct {
+	source string
+	id     int
+}
+
+type document struct {
+	repository *domain.ListRepoOutput
+	terms      map[string]float64 // weighted frequencies
+	length     float64
+}
+
+// Index is an in-memory inverted index ranking the repositories with BM25 over their names, full names and descriptions,
+// once full the oldest indexed repository is dropped for every new one
+type Index struct {
+	sync.RWMutex
+	capacity    int
+	documents   map[docKey]*document
+	postings    map[string]map[docKey]float64
+	totalLength float64
+	order       []docKey // insertion order, for the eviction
+}
+
+// ProvideIndex builds the index and loads the repositories of the store, nil when the search is disabled
+func ProvideIndex(config *conf.Config, store port.RepoStoreInterface, sources port.Sources) port.SearchIndexInterface {
+	if !config.SearchEnabled {
+		return nil
+	}
+
+	index := NewIndex(config.SearchMaxDocuments)
+	if store == nil {
+		return index
+	}
+	for name := range sources {
+		err := store.List(name, func(stored *domain.StoredRepository) bool {
+			index.Index(name, stored.ID, stored.Repository)
+			return true
+		})
+		if err != nil {
+			log.Errorf("couldn't index the stored repositories of %s: %s", name, err.Error())
+		}
+	}
+	return index
+}
+
+func NewIndex(capacity int) *Index {
+	return &Index{
+		capacity:  max(capacity, 1),
+		documents: make(map[docKey]*document),
+		postings:  make(map[string]map[docKey]float64),
+	}
+}
+
+func fields(repository *domain.ListRepoOutput) map[string]string {
+	return map[string]string{
+		NameField:        repository.FullName[strings.LastIndex(repository.FullName, "/")+1:],
+		FullNameField:    repository.FullName,
+		DescriptionField: repository.Description,
+	}
+}
+
+// Index adds the repository or replaces its previous version
+func (i *Index) Index(source string, id int, repository *domain.ListRepoOutput) {
+	doc := &document{repository: repository, terms: make(map[string]float64)}
+	for field, text := range fields(repository) {
+		for _, t := range tokenize(text) {
+			doc.terms[t.term] += fieldWeights[field]
+			doc.length += fieldWeights[field]
+		}
+	}
+
+	i.Lock()
+	defer i.Unlock()
+
+	key := docKey{source: source, id: id}
+	if _, ok := i.documents[key]; ok {
+		i.remove(key)
+	} else {
+		i.order = append(i.order, key)
+	}
+	i.documents[key] = doc
+	i.totalLength += doc.length
+	for term, frequency := range doc.terms {
+		if i.postings[term] == nil {
+			i.postings[term] = make(map[docKey]float64)
+		}
+		i.postings[term][key] = frequency
+	}
+
+	for len(i.documents) > i.capacity {
+		oldest := i.order[0]
+		i.order = i.order[1:]
+		i.remove(oldest)
+	}
+}
+
+func (i *Index) remove(key docKey) {
+	doc, ok := i.documents[key]
+	if !ok {
+		return
+	}
+	for term := range doc.terms {
+		delete(i.postings[term], key)
+		if len(i.postings[term]) == 0 {
+			delete(i.postings, term)
+		}
+	}
+	i.totalLength -= doc.length
+	delete(i.documents, key)
+}
+
+func (i *Index) Search(text, source string, limit int, accept func(repository *domain.ListRepoOutput) bool) []*domain.SearchHit {
+	terms := make(map[string]struct{})
+	for _, t := range tokenize(text) {
+		terms[t.term] = struct{}{}
+	}
+
+	i.RLock()
+	scores := make(map[docKey]float64)
+	total := float64(len(i.documents))
+	averageLength := i.totalLength / math.Max(total, 1)
+	for term := range terms {
+		postings := i.postings[term]
+		idf := math.Log(1 + (total-float64(len(postings))+0.5)/(float64(len(postings))+0.5)) //nolint: gomnd
+		for key, frequency := range postings {
+			if source != "" && key.source != source {
+				continue
+			}
+			length := i.documents[key].length
+			scores[key] += idf * frequency * (k1 + 1) / (frequency + k1*(1-b+b*length/averageLength))
+		}
+	}
+
+	hits := make([]*domain.SearchHit, 0, len(scores))
+	for key, score := range scores {
+		hits = append(hits, &domain.SearchHit{
+			Source:     key.source,
+			ID:         key.id,
+			Score:      score,
+			Repository: i.documents[key].repository,
+		})
+	}
+	i.RUnlock()
+
+	sort.Slice(hits, func(x, y int) bool {
+		if hits[x].Score != hits[y].Score {
+			return hits[x].Score > hits[y].Score
+		}
+		return hits[x].ID > hits[y].ID // the newest first
+	})
+
+	accepted := make([]*domain.SearchHit, 0, min(limit, len(hits)))
+	for _, hit := range hits {
+		if len(accepted) >= limit {
+			break
+		}
+		if accept != nil && !accept(hit.Repository) {
+			continue
+		}
+		hit.Highlights = make(map[string]string)
+		for field, value := range fields(hit.Repository) {
+			if highlighted := highlight(value, terms, snippetSize); highlighted != "" {
+				hit.Highlights[field] = highlighted
+			}
+		}
+		accepted = append(accepted, hit)
+	}
+	return accepted
+}
@@ -0,0 +1,100 @@
+package search
+
+import (
+	"testing"
+
+	"scalingo/internal/core/domain"
+
+	"github.com/stretchr/testify/assert"
+	s "github.com/stretchr/testify/suite"
+)
+
+type IndexSuite struct {
+	s.Suite
+	index *Index
+}
+
+func (suite *IndexSuite) SetupTest() {
+	suite.index = NewIndex(10)
+	suite.index.Index("github", 1, &domain.ListRepoOutput{
+		FullName:    "john_doe/http-router",
+		Description: "A fast HTTP router for Go services, routing requests with zero allocations",
+	})
+	suite.index.Index("github", 2, &domain.ListRepoOutput{
+		FullName:    "jane_doe/dotfiles",
+		Description: "My configuration files, including a tiny router for my shell aliases",
+	})
+	suite.index.Index("gitlab", 3, &domain.ListRepoOutput{
+		FullName:    "group/sub/KubernetesOperators",
+		Description: "Operators <managing> clusters",
+	})
+}
+
+func (suite *IndexSuite) TearDownTest() {}
+
+func (suite *IndexSuite) TestStem() {
+	for word, expected := range map[string]string{
+		"routers":        "router",
+		"routing":        "rout",
+		"connections":    "connect",
+		"connected":      "connect",
+		"relational":     "relat",
+		"generalization": "gener",
+		"ponies":         "poni",
+		"hopping":        "hop",
+		"go":             "go",
+		"v2":             "v2",
+	} {
+		assert.Equal(suite.T(), expected, stem(word), word)
+	}
+}
+
+func (suite *IndexSuite) TestSearch_RankedByBM25() {
+	hits := suite.index.Search("routers", "", 10, nil)
+
+	assert.Equal(suite.T(), 2, len(hits))
+	// The name match counts more than the description one
+	assert.Equal(suite.T(), 1, hits[0].ID)
+	assert.Greater(suite.T(), hits[0].Score, hits[1].Score)
+	assert.Equal(suite.T(), "http-<em>router</em>", hits[0].Highlights[NameField])
+	assert.Contains(suite.T(), hits[1].Highlights[DescriptionField], "a tiny <em>router</em> for")
+}
+
+func (suite *IndexSuite) TestSearch_CamelCaseSourceAndFilter() {
+	hits := suite.index.Search("kubernetes operator", "gitlab", 10, nil)
+
+	assert.Equal(suite.T(), 1, len(hits))
+	assert.Equal(suite.T(), "<em>Kubernetes</em><em>Operators</em>", hits[0].Highlights[NameField])
+	assert.Equal(suite.T(), "<em>Operators</em> &lt;managing&gt; clusters", hits[0].Highlights[DescriptionField])
+
+	assert.Empty(suite.T(), suite.index.Search("kubernetes", "github", 10, nil))
+	assert.Empty(suite.T(), suite.index.Search("router", "", 10, func(repository *domain.ListRepoOutput) bool { return false }))
+}
+
+func (suite *IndexSuite) TestIndex_ReplaceAndEvict() {
+	suite.index.Index("github", 2, &domain.ListRepoOutput{FullName: "jane_doe/dotfiles", Description: "Shell aliases"})
+	assert.Equal(suite.T(), 1, len(suite.index.Search("router", "", 10, nil)))
+
+	small := NewIndex(1)
+	small.Index("github", 1, &domain.ListRepoOutput{FullName: "john_doe/router"})
+	small.Index("github", 2, &domain.ListRepoOutput{FullName: "jane_doe/router"})
+	hits := small.Search("router", "", 10, nil)
+	assert.Equal(suite.T(), 1, len(hits))
+	assert.Equal(suite.T(), 2, hits[0].ID)
+}
+
+func (suite *IndexSuite) TestHighlight_Snippet() {
+	text := "Lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod tempor incididunt ut labore et dolore magna aliqua ut enim ad minim " +
+		"veniam quis nostrud exercitation ullamco laboris nisi ut aliquip ex ea commodo consequat duis aute irure dolor " +
+		"in reprehenderit in voluptate velit esse cillum dolore eu fugiat nulla pariatur router"
+
+	snippet := highlight(text, map[string]struct{}{"router": {}}, snippetSize)
+
+	assert.True(suite.T(), len(snippet) < len(text))
+	assert.Contains(suite.T(), snippet, "<em>router</em>")
+	assert.Contains(suite.T(), snippet, "…")
+}
+
+func TestIndexSuite(t *testing.T) {
+	s.Run(t, new(IndexSuite))
+}
@@ -0,0 +1,98 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// token is a stemmed term along with the byte offsets of the word it comes from, used by the highlighting
+type token struct {
+	term       string
+	start, end int
+}
+
+var stopWords = map[string]struct{}{
+	"a": {}, "an": {}, "and": {}, "are": {}, "as": {}, "at": {}, "be": {}, "by": {}, "for": {}, "from": {},
+	"in": {}, "is": {}, "it": {}, "of": {}, "on": {}, "or": {}, "that": {}, "the": {}, "this": {}, "to": {},
+	"with": {},
+}
+
+// tokenize splits the text on anything but letters and digits, and on the camelCase boundaries of the names,
+// then lowercases, drops the stop words and stems the words
+func tokenize(text string) []token {
+	tokens := make([]token, 0)
+	start := -1
+	var previous rune
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		word := strings.ToLower(text[start:end])
+		if _, stop := stopWords[word]; !stop {
+			tokens = append(tokens, token{term: stem(word), start: start, end: end})
+		}
+		start = -1
+	}
+
+	for i, r := range text {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush(i)
+		case start >= 0 && unicode.IsUpper(r) && unicode.IsLower(previous):
+			flush(i)
+			start = i
+		case start < 0:
+			start = i
+		}
+		previous = r
+	}
+	flush(len(text))
+	return tokens
+}
+
+// highlight wraps the words of the text matching the terms in <em> tags and escapes the rest as HTML, long texts
+// are cut to a snippet around the first match, an empty string is returned when nothing matches
+func highlight(text string, terms map[string]struct{}, snippetSize int) string {
+	matches := make([]token, 0)
+	for _, t := range tokenize(text) {
+		if _, ok := terms[t.term]; ok {
+			matches = append(matches, t)
+		}
+	}
+	if len(matches) == 0 {
+		return ""
+	}
+
+	from, to := 0, len(text)
+	if len(text) > snippetSize {
+		from = max(matches[0].start-snippetSize/4, 0) //nolint: gomnd
+		to = min(from+snippetSize, len(text))
+		for from > 0 && !utf8.RuneStart(text[from]) {
+			from--
+		}
+		for to < len(text) && !utf8.RuneStart(text[to]) {
+			to++
+		}
+	}
+
+	var snippet strings.Builder
+	if from > 0 {
+		snippet.WriteString("…")
+	}
+	cursor := from
+	for _, match := range matches {
+		if match.start < from || match.end > to {
+			continue
+		}
+		snippet.WriteString(html.EscapeString(text[cursor:match.start]))
+		snippet.WriteString("<em>" + html.EscapeString(text[match.start:match.end]) + "</em>")
+		cursor = match.end
+	}
+	snippet.WriteString(html.EscapeString(text[cursor:to]))
+	if to < len(text) {
+		snippet.WriteString("…")
+	}
+	return snippet.String()
+}
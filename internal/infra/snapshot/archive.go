@@ -0,0 +1,197 @@
+package snapshot
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"strconv"
+
+	"scalingo/internal/core/domain"
+	"scalingo/internal/core/port"
+	"scalingo/internal/shared"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	headerLine     = "header"
+	repositoryLine = "repository"
+	manifestLine   = "manifest"
+
+	corruptedPrefix = "corrupted snapshot: "
+
+	// Longest line accepted by the import, a repository with a very long description
+	maxLineSize = 1 << 20
+)
+
+// line is a JSON line of the archive: the header, then a line per repository and the manifest
+type line struct {
+	Type       string                   `json:"type"`
+	Header     *domain.SnapshotHeader   `json:"header,omitempty"`
+	Repository *domain.StoredRepository `json:"repository,omitempty"`
+	Manifest   *domain.SnapshotManifest `json:"manifest,omitempty"`
+}
+
+// Archive is a zstd compressed JSON lines snapshot, the manifest closing it holds the SHA-256 of the repository lines
+// of every source
+type Archive struct{}
+
+func ProvideArchive() port.SnapshotArchiveInterface {
+	return &Archive{}
+}
+
+type writer struct {
+	encoder  *zstd.Encoder
+	manifest *domain.SnapshotManifest
+	hashes   map[string]hash.Hash
+}
+
+func (a *Archive) NewWriter(w io.Writer, header *domain.SnapshotHeader) (port.SnapshotWriterInterface, error) {
+	encoder, err := zstd.NewWriter(w)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &writer{
+		encoder:  encoder,
+		manifest: &domain.SnapshotManifest{Sources: make(map[string]*domain.SnapshotChecksum)},
+		hashes:   make(map[string]hash.Hash),
+	}
+	if _, err = archive.writeLine(&line{Type: headerLine, Header: header}); err != nil {
+		_ = encoder.Close()
+		return nil, err
+	}
+	return archive, nil
+}
+
+func (w *writer) writeLine(l *line) ([]byte, error) {
+	serialized, err := jsoniter.Marshal(l)
+	if err != nil {
+		return nil, errors.New("error while serializing snapshot line: " + err.Error())
+	}
+	if _, err = w.encoder.Write(append(serialized, '\n')); err != nil {
+		return nil, err
+	}
+	return serialized, nil
+}
+
+func (w *writer) Write(repository *domain.StoredRepository) error {
+	serialized, err := w.writeLine(&line{Type: repositoryLine, Repository: repository})
+	if err != nil {
+		return err
+	}
+
+	checksum, ok := w.manifest.Sources[repository.Source]
+	if !ok {
+		checksum = &domain.SnapshotChecksum{MinID: repository.ID, MaxID: repository.ID}
+		w.manifest.Sources[repository.Source] = checksum
+		w.hashes[repository.Source] = sha256.New()
+	}
+	checksum.Repositories++
+	checksum.MinID = min(checksum.MinID, repository.ID)
+	checksum.MaxID = max(checksum.MaxID, repository.ID)
+	w.hashes[repository.Source].Write(serialized)
+	w.manifest.Repositories++
+	return nil
+}
+
+func (w *writer) Close() (*domain.SnapshotManifest, error) {
+	for source, checksum := range w.manifest.Sources {
+		checksum.SHA256 = hex.EncodeToString(w.hashes[source].Sum(nil))
+	}
+	if _, err := w.writeLine(&line{Type: manifestLine, Manifest: w.manifest}); err != nil {
+		_ = w.encoder.Close()
+		return nil, err
+	}
+	if err := w.encoder.Close(); err != nil {
+		return nil, err
+	}
+	return w.manifest, nil
+}
+
+func (a *Archive) Read(r io.Reader) (*domain.Snapshot, error) {
+	repositories := make([]*domain.StoredRepository, 0)
+	snapshot, err := a.Stream(r, func(repository *domain.StoredRepository) error {
+		repositories = append(repositories, repository)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	snapshot.Repositories = repositories
+	return snapshot, nil
+}
+
+//nolint:gocyclo
+func (a *Archive) Stream(r io.Reader, fn func(repository *domain.StoredRepository) error) (*domain.Snapshot, error) {
+	decoder, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer decoder.Close()
+
+	snapshot := &domain.Snapshot{}
+	hashes := make(map[string]hash.Hash)
+	repositories := 0
+	scanner := bufio.NewScanner(decoder)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLineSize)
+	for number := 1; scanner.Scan(); number++ {
+		if snapshot.Manifest != nil {
+			return nil, corrupted(number, "line after the manifest")
+		}
+
+		l := &line{}
+		if err = jsoniter.Unmarshal(scanner.Bytes(), l); err != nil {
+			return nil, corrupted(number, err.Error())
+		}
+		switch {
+		case number == 1 && (l.Type != headerLine || l.Header == nil):
+			return nil, corrupted(number, "missing header")
+		case number == 1:
+			if l.Header.Version > domain.SnapshotVersion {
+				return nil, errors.New("unsupported snapshot version: " + strconv.Itoa(l.Header.Version))
+			}
+			snapshot.Header = l.Header
+		case l.Type == repositoryLine && l.Repository != nil && l.Repository.Repository != nil:
+			if hashes[l.Repository.Source] == nil {
+				hashes[l.Repository.Source] = sha256.New()
+			}
+			hashes[l.Repository.Source].Write(scanner.Bytes())
+			repositories++
+			if err = fn(l.Repository); err != nil {
+				return nil, err
+			}
+		case l.Type == manifestLine && l.Manifest != nil:
+			snapshot.Manifest = l.Manifest
+		default:
+			return nil, corrupted(number, "unexpected "+l.Type+" line")
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, errors.New("error while reading snapshot: " + err.Error())
+	}
+	if snapshot.Manifest == nil {
+		return nil, corrupted(0, "missing manifest, the archive is truncated")
+	}
+
+	if snapshot.Manifest.Repositories != repositories || len(snapshot.Manifest.Sources) != len(hashes) {
+		return nil, corrupted(0, "the manifest doesn't match the repositories")
+	}
+	for source, checksum := range snapshot.Manifest.Sources {
+		if hashes[source] == nil || hex.EncodeToString(hashes[source].Sum(nil)) != checksum.SHA256 {
+			return nil, corrupted(0, "checksum mismatch for "+source)
+		}
+	}
+	return snapshot, nil
+}
+
+func corrupted(number int, reason string) error {
+	if number > 0 {
+		reason = "line " + strconv.Itoa(number) + shared.Separator + reason
+	}
+	return errors.New(corruptedPrefix + reason)
+}
@@ -0,0 +1,108 @@
+package snapshot
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"scalingo/internal/core/domain"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	s "github.com/stretchr/testify/suite"
+)
+
+type ArchiveSuite struct {
+	s.Suite
+	archive *Archive
+}
+
+func (suite *ArchiveSuite) SetupTest() {
+	suite.archive = &Archive{}
+}
+
+func (suite *ArchiveSuite) write(repositories ...*domain.StoredRepository) []byte {
+	var buffer bytes.Buffer
+	writer, err := suite.archive.NewWriter(&buffer, &domain.SnapshotHeader{
+		Version: domain.SnapshotVersion,
+		Sources: map[string]*domain.SnapshotSource{"github": {Checkpoint: 42}},
+	})
+	assert.NoError(suite.T(), err)
+	for _, repository := range repositories {
+		assert.NoError(suite.T(), writer.Write(repository))
+	}
+	_, err = writer.Close()
+	assert.NoError(suite.T(), err)
+	return buffer.Bytes()
+}
+
+func stored(source string, id int) *domain.StoredRepository {
+	return &domain.StoredRepository{
+		Source:     source,
+		ID:         id,
+		FetchedAt:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Repository: &domain.ListRepoOutput{FullName: "john_doe/repo", License: "mit", Languages: map[string]int{"Go": 10}},
+	}
+}
+
+// rewrite decompresses the archive, edits its lines and compresses it back
+func rewrite(archive []byte, edit func(lines []string) []string) []byte {
+	decoder, _ := zstd.NewReader(nil)
+	decompressed, _ := decoder.DecodeAll(archive, nil)
+	lines := edit(strings.Split(strings.TrimSuffix(string(decompressed), "\n"), "\n"))
+	encoder, _ := zstd.NewWriter(nil)
+	return encoder.EncodeAll([]byte(strings.Join(lines, "\n")+"\n"), nil)
+}
+
+func (suite *ArchiveSuite) TestRoundTrip() {
+	archive := suite.write(stored("github", 3), stored("github", 1), stored("gitlab", 7))
+
+	snapshot, err := suite.archive.Read(bytes.NewReader(archive))
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 42, snapshot.Header.Sources["github"].Checkpoint)
+	assert.Equal(suite.T(), 3, len(snapshot.Repositories))
+	assert.Equal(suite.T(), map[string]int{"Go": 10}, snapshot.Repositories[0].Repository.Languages)
+	assert.Equal(suite.T(), 3, snapshot.Manifest.Repositories)
+	assert.Equal(suite.T(), 1, snapshot.Manifest.Sources["github"].MinID)
+	assert.Equal(suite.T(), 3, snapshot.Manifest.Sources["github"].MaxID)
+	assert.Equal(suite.T(), 2, snapshot.Manifest.Sources["github"].Repositories)
+	assert.Len(suite.T(), snapshot.Manifest.Sources["gitlab"].SHA256, 64)
+}
+
+func (suite *ArchiveSuite) TestChecksumMismatch() {
+	archive := rewrite(suite.write(stored("github", 3)), func(lines []string) []string {
+		lines[1] = strings.Replace(lines[1], "mit", "gpl", 1)
+		return lines
+	})
+
+	_, err := suite.archive.Read(bytes.NewReader(archive))
+
+	assert.EqualError(suite.T(), err, "corrupted snapshot: checksum mismatch for github")
+}
+
+func (suite *ArchiveSuite) TestTruncated() {
+	archive := rewrite(suite.write(stored("github", 3)), func(lines []string) []string {
+		return lines[:len(lines)-1]
+	})
+
+	_, err := suite.archive.Read(bytes.NewReader(archive))
+
+	assert.EqualError(suite.T(), err, "corrupted snapshot: missing manifest, the archive is truncated")
+}
+
+func (suite *ArchiveSuite) TestNewerVersion() {
+	archive := rewrite(suite.write(), func(lines []string) []string {
+		lines[0] = strings.Replace(lines[0], `"version":1`, `"version":2`, 1)
+		return lines
+	})
+
+	_, err := suite.archive.Read(bytes.NewReader(archive))
+
+	assert.EqualError(suite.T(), err, "unsupported snapshot version: 2")
+}
+
+func TestArchiveSuite(t *testing.T) {
+	s.Run(t, new(ArchiveSuite))
+}
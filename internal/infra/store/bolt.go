@@ -0,0 +1,358 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"scalingo/internal/core/domain"
+	"scalingo/internal/core/port"
+	conf "scalingo/internal/infra/config"
+	"scalingo/internal/shared"
+
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	compactSuffix = ".compact"
+	// Size of the transactions copying the database during a compaction
+	compactTxMaxSize = 1 << 16
+
+	// Unix nano fetch time prefixing every value, the pruning doesn't have to decode the repositories
+	fetchedAtSize = 8
+)
+
+var (
+	repositoriesBucket = []byte("repositories")
+	checkpointsBucket  = []byte("checkpoints")
+)
+
+// Bolt is the embedded store of the enriched repositories, a single bbolt file keyed by source and repository ID
+type Bolt struct {
+	sync.RWMutex // guards the db swap of the compaction
+	db           *bolt.DB
+	path         string
+	retention    time.Duration
+	maintenance  time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// ProvideBolt opens the store file, nil when no STORE_PATH is configured
+func ProvideBolt(config *conf.Config) *Bolt {
+	if config.StorePath == "" {
+		return nil
+	}
+
+	store, err := NewBolt(config.StorePath, config.StoreRetention, config.StoreCompactionInterval)
+	if err != nil {
+		panic("Error opening the repository store: " + err.Error())
+	}
+	return store
+}
+
+// ProvideRepoStore exposes the store to the core, as a nil interface when the persistence is disabled
+func ProvideRepoStore(store *Bolt) port.RepoStoreInterface {
+	if store == nil {
+		return nil
+	}
+	return store
+}
+
+func NewBolt(path string, retention, maintenance time.Duration) (*Bolt, error) {
+	db, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Bolt{db: db, path: path, retention: retention, maintenance: maintenance}, nil
+}
+
+func open(path string) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(repositoriesBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(checkpointsBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func key(source string, id int) []byte {
+	k := make([]byte, len(source)+1+8)
+	copy(k, source)
+	binary.BigEndian.PutUint64(k[len(source)+1:], uint64(id))
+	return k
+}
+
+func (b *Bolt) Get(source string, id int) (*domain.StoredRepository, bool, error) {
+	b.RLock()
+	defer b.RUnlock()
+
+	var value []byte
+	err := b.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(repositoriesBucket).Get(key(source, id)); v != nil {
+			value = append([]byte{}, v...) // only valid during the transaction
+		}
+		return nil
+	})
+	if err != nil || value == nil {
+		return nil, false, err
+	}
+	if len(value) < fetchedAtSize {
+		return nil, false, errors.New("corrupted stored repository: " + source + shared.Separator + strconv.Itoa(id))
+	}
+	if b.expired(fetchedAt(value)) {
+		return nil, false, nil
+	}
+
+	repository := &domain.StoredRepository{}
+	if err = jsoniter.Unmarshal(value[fetchedAtSize:], repository); err != nil {
+		return nil, false, errors.New("error while deserializing stored repository: " + err.Error())
+	}
+	return repository, true, nil
+}
+
+func (b *Bolt) Save(repository *domain.StoredRepository) error {
+	value, err := encode(repository)
+	if err != nil {
+		return err
+	}
+
+	b.RLock()
+	defer b.RUnlock()
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(repositoriesBucket).Put(key(repository.Source, repository.ID), value)
+	})
+}
+
+// Batch commits all the saves at once, a single fsync instead of one per repository
+func (b *Bolt) Batch(fn func(save func(repository *domain.StoredRepository) error) error) error {
+	b.RLock()
+	defer b.RUnlock()
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(repositoriesBucket)
+		return fn(func(repository *domain.StoredRepository) error {
+			value, err := encode(repository)
+			if err != nil {
+				return err
+			}
+			return bucket.Put(key(repository.Source, repository.ID), value)
+		})
+	})
+}
+
+// encode prefixes the serialized repository with its fetch time, read without deserializing it
+func encode(repository *domain.StoredRepository) ([]byte, error) {
+	serialized, err := jsoniter.Marshal(repository)
+	if err != nil {
+		return nil, errors.New("error while serializing repository: " + err.Error())
+	}
+	value := make([]byte, fetchedAtSize, fetchedAtSize+len(serialized))
+	binary.BigEndian.PutUint64(value, uint64(repository.FetchedAt.UnixNano()))
+	return append(value, serialized...), nil
+}
+
+// List walks the keys of the source backwards, the big endian IDs keep them sorted from the oldest to the newest
+func (b *Bolt) List(source string, fn func(repository *domain.StoredRepository) bool) error {
+	b.RLock()
+	defer b.RUnlock()
+
+	prefix := append([]byte(source), 0)
+	end := append([]byte(source), 1)
+	return b.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(repositoriesBucket).Cursor()
+		k, v := cursor.Seek(end)
+		if k == nil {
+			k, v = cursor.Last()
+		} else {
+			k, v = cursor.Prev()
+		}
+		for ; k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Prev() {
+			if len(v) < fetchedAtSize || b.expired(fetchedAt(v)) {
+				continue
+			}
+			repository := &domain.StoredRepository{}
+			if err := jsoniter.Unmarshal(v[fetchedAtSize:], repository); err != nil {
+				return errors.New("error while deserializing stored repository: " + err.Error())
+			}
+			if !fn(repository) {
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+// Sources jumps from one source prefix to the next, the keys being sorted by source first
+func (b *Bolt) Sources() ([]string, error) {
+	b.RLock()
+	defer b.RUnlock()
+
+	sources := make([]string, 0)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(repositoriesBucket).Cursor()
+		for k, _ := cursor.First(); k != nil; {
+			separator := bytes.IndexByte(k, 0)
+			if separator < 0 {
+				k, _ = cursor.Next()
+				continue
+			}
+			sources = append(sources, string(k[:separator]))
+			k, _ = cursor.Seek(append(append([]byte{}, k[:separator]...), 1))
+		}
+		return nil
+	})
+	return sources, err
+}
+
+func (b *Bolt) Checkpoint(source string) (int, bool, error) {
+	b.RLock()
+	defer b.RUnlock()
+
+	id, found := 0, false
+	err := b.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(checkpointsBucket).Get([]byte(source)); len(v) == 8 {
+			id, found = int(binary.BigEndian.Uint64(v)), true
+		}
+		return nil
+	})
+	return id, found, err
+}
+
+func (b *Bolt) SetCheckpoint(source string, id int) error {
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, uint64(id))
+
+	b.RLock()
+	defer b.RUnlock()
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(checkpointsBucket).Put([]byte(source), value)
+	})
+}
+
+func (b *Bolt) Prune(before time.Time) (int, error) {
+	b.RLock()
+	defer b.RUnlock()
+
+	pruned := 0
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(repositoriesBucket)
+		// Deleting while iterating makes the cursor skip items
+		expired := make([][]byte, 0)
+		err := bucket.ForEach(func(k, v []byte) error {
+			if len(v) < fetchedAtSize || fetchedAt(v).Before(before) {
+				expired = append(expired, append([]byte{}, k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err = bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		pruned = len(expired)
+		return nil
+	})
+	return pruned, err
+}
+
+// Compact copies the live repositories to a new file replacing the current one, bbolt never shrinks its file otherwise
+func (b *Bolt) Compact() error {
+	b.Lock()
+	defer b.Unlock()
+
+	compacted := b.path + compactSuffix
+	dst, err := bolt.Open(compacted, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return err
+	}
+	if err = bolt.Compact(dst, b.db, compactTxMaxSize); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(compacted)
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+
+	if err = b.db.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(compacted, b.path); err != nil {
+		log.Errorf("couldn't replace the store by its compacted copy: %s", err.Error())
+	}
+	b.db, err = open(b.path)
+	return err
+}
+
+// Start prunes the repositories older than the retention period and compacts the store periodically
+func (b *Bolt) Start(ctx context.Context) {
+	if b.maintenance <= 0 {
+		return
+	}
+	ctx, b.cancel = context.WithCancel(ctx)
+	b.done = make(chan struct{})
+
+	go func() {
+		defer close(b.done)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(b.maintenance):
+			}
+
+			if b.retention > 0 {
+				pruned, err := b.Prune(time.Now().Add(-b.retention))
+				if err != nil {
+					log.Errorf("Store pruning error: %s", err.Error())
+				}
+				log.Infof("%d repositories pruned from the store", pruned)
+			}
+			if err := b.Compact(); err != nil {
+				log.Errorf("Store compaction error: %s", err.Error())
+			}
+		}
+	}()
+}
+
+// Close stops the maintenance and closes the store file
+func (b *Bolt) Close() error {
+	if b.cancel != nil {
+		b.cancel()
+		<-b.done
+	}
+
+	b.Lock()
+	defer b.Unlock()
+	return b.db.Close()
+}
+
+func (b *Bolt) expired(fetched time.Time) bool {
+	return b.retention > 0 && time.Since(fetched) > b.retention
+}
+
+func fetchedAt(value []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(value[:fetchedAtSize])))
+}
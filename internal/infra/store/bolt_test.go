@@ -0,0 +1,167 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"scalingo/internal/core/domain"
+
+	"github.com/stretchr/testify/assert"
+	s "github.com/stretchr/testify/suite"
+)
+
+type BoltSuite struct {
+	s.Suite
+	path  string
+	store *Bolt
+}
+
+func (suite *BoltSuite) SetupTest() {
+	suite.path = filepath.Join(suite.T().TempDir(), "repositories.db")
+	store, err := NewBolt(suite.path, time.Hour, 0)
+	assert.NoError(suite.T(), err)
+	suite.store = store
+}
+
+func (suite *BoltSuite) TearDownTest() {
+	_ = suite.store.Close()
+}
+
+func (suite *BoltSuite) save(source string, id int, fetchedAt time.Time) {
+	err := suite.store.Save(&domain.StoredRepository{
+		Source:     source,
+		ID:         id,
+		FetchedAt:  fetchedAt,
+		Repository: &domain.ListRepoOutput{Source: source, FullName: "john_doe/repo", Languages: map[string]int{"Go": 10}},
+	})
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *BoltSuite) TestSaveAndGet() {
+	suite.save("github", 1, time.Now())
+	suite.save("gitlab", 1, time.Now())
+
+	repository, ok, err := suite.store.Get("github", 1)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), "john_doe/repo", repository.Repository.FullName)
+	assert.Equal(suite.T(), map[string]int{"Go": 10}, repository.Repository.Languages)
+
+	_, ok, err = suite.store.Get("github", 2)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), ok)
+}
+
+func (suite *BoltSuite) TestRetention() {
+	suite.save("github", 1, time.Now().Add(-2*time.Hour))
+	suite.save("github", 2, time.Now())
+
+	_, ok, err := suite.store.Get("github", 1)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), ok)
+
+	pruned, err := suite.store.Prune(time.Now().Add(-time.Hour))
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, pruned)
+}
+
+func (suite *BoltSuite) TestCompactAndReopen() {
+	for id := 0; id < 1000; id++ {
+		suite.save("github", id, time.Now().Add(-2*time.Hour))
+	}
+	suite.save("github", 1000, time.Now())
+	_, err := suite.store.Prune(time.Now().Add(-time.Hour))
+	assert.NoError(suite.T(), err)
+	before, err := os.Stat(suite.path)
+	assert.NoError(suite.T(), err)
+
+	assert.NoError(suite.T(), suite.store.Compact())
+	after, err := os.Stat(suite.path)
+	assert.NoError(suite.T(), err)
+	assert.Less(suite.T(), after.Size(), before.Size())
+
+	// Survives a restart
+	assert.NoError(suite.T(), suite.store.Close())
+	suite.store, err = NewBolt(suite.path, time.Hour, 0)
+	assert.NoError(suite.T(), err)
+	_, ok, err := suite.store.Get("github", 1000)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), ok)
+}
+
+func (suite *BoltSuite) TestListNewestFirst() {
+	suite.save("github", 2, time.Now())
+	suite.save("github", 300, time.Now())
+	suite.save("github", 10, time.Now().Add(-2*time.Hour))
+	suite.save("gitlab", 1000, time.Now())
+	suite.save("git", 5000, time.Now())
+
+	ids := make([]int, 0)
+	err := suite.store.List("github", func(repository *domain.StoredRepository) bool {
+		ids = append(ids, repository.ID)
+		return true
+	})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []int{300, 2}, ids)
+}
+
+func (suite *BoltSuite) TestSources() {
+	suite.save("github", 2, time.Now())
+	suite.save("github", 300, time.Now())
+	suite.save("gitlab", 1000, time.Now())
+	suite.save("git", 5000, time.Now())
+
+	sources, err := suite.store.Sources()
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"git", "github", "gitlab"}, sources)
+}
+
+func (suite *BoltSuite) TestBatch() {
+	repository := func(id int) *domain.StoredRepository {
+		return &domain.StoredRepository{Source: "github", ID: id, FetchedAt: time.Now(), Repository: &domain.ListRepoOutput{}}
+	}
+
+	err := suite.store.Batch(func(save func(repository *domain.StoredRepository) error) error {
+		for id := 1; id <= 3; id++ {
+			if err := save(repository(id)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.NoError(suite.T(), err)
+	_, ok, err := suite.store.Get("github", 3)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), ok)
+
+	// Nothing is saved by a failed batch
+	err = suite.store.Batch(func(save func(repository *domain.StoredRepository) error) error {
+		assert.NoError(suite.T(), save(repository(4)))
+		return errors.New("corrupted snapshot")
+	})
+	assert.Error(suite.T(), err)
+	_, ok, err = suite.store.Get("github", 4)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), ok)
+}
+
+func (suite *BoltSuite) TestCheckpoint() {
+	_, found, err := suite.store.Checkpoint("github")
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), found)
+
+	assert.NoError(suite.T(), suite.store.SetCheckpoint("github", 755000150))
+	id, found, err := suite.store.Checkpoint("github")
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), found)
+	assert.Equal(suite.T(), 755000150, id)
+}
+
+func TestBoltSuite(t *testing.T) {
+	s.Run(t, new(BoltSuite))
+}
@@ -9,10 +9,29 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func ProvideRouter(ctx context.Context, repositoriesController *controller.RepoHTTPHandler, config *conf.Config) *gin.Engine {
+func ProvideRouter(
+	ctx context.Context,
+	repositoriesController *controller.RepoHTTPHandler,
+	eventsController *controller.EventHTTPHandler,
+	adminController *controller.AdminHTTPHandler,
+	config *conf.Config,
+) *gin.Engine {
 	gin.SetMode(config.GinMode)
 	g := gin.Default()
 
 	g.GET("/repositories", func(c *gin.Context) { repositoriesController.RepoController(ctx, c) })
+	g.GET("/events", func(c *gin.Context) { eventsController.EventController(ctx, c) })
+
+	// The admin endpoints are not served without a token to require
+	if config.AdminToken == "" {
+		return g
+	}
+	admin := g.Group("/admin", adminController.Authenticate)
+	admin.GET("/tokens", adminController.TokenUsageController)
+	admin.GET("/cache", adminController.CacheStatsController)
+	admin.DELETE("/cache", adminController.CachePurgeController)
+	admin.DELETE("/responses", adminController.ResponsesPurgeController)
+	admin.GET("/snapshot", adminController.SnapshotExportController)
+	admin.POST("/snapshot", adminController.SnapshotImportController)
 	return g
 }
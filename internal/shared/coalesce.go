@@ -0,0 +1,57 @@
+package shared
+
+import (
+	"context"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+)
+
+// Coalescer shares the result of an in-flight call with the concurrent callers of the same key,
+// only the first one reaches fn and the others wait for its result
+type Coalescer struct {
+	name  string
+	group singleflight.Group
+	saved atomic.Int64
+}
+
+func NewCoalescer(name string) *Coalescer {
+	return &Coalescer{name: name}
+}
+
+func (c *Coalescer) Do(key string, fn func() (any, error)) (any, error) {
+	leader := false
+	value, err, shared := c.group.Do(key, func() (any, error) {
+		leader = true
+		return fn()
+	})
+	if shared && !leader {
+		log.Infof("%s coalesced with an in-flight call for %s, %d calls saved", c.name, key, c.saved.Add(1))
+	}
+	return value, err
+}
+
+// DoContext runs fn as Do does, but each caller only waits for the shared result until its own context is done,
+// fn must not depend on the context of the caller who happened to start it
+func (c *Coalescer) DoContext(ctx context.Context, key string, fn func() (any, error)) (any, error) {
+	leader := false
+	results := c.group.DoChan(key, func() (any, error) {
+		leader = true
+		return fn()
+	})
+	select {
+	case result := <-results:
+		if result.Shared && !leader {
+			log.Infof("%s coalesced with an in-flight call for %s, %d calls saved", c.name, key, c.saved.Add(1))
+		}
+		return result.Val, result.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Saved returns the number of calls answered by an in-flight one
+func (c *Coalescer) Saved() int64 {
+	return c.saved.Load()
+}